@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/example/aws-calculator-gen/internal/command"
 )
@@ -14,7 +15,12 @@ Usage:
   aws-calculator-gen <command> [--params key=value ...]
 
 Available commands:
-  map    Create MAP estimate
+  map              Create MAP estimate
+  plan             Print the MAP plan without opening the browser
+  catalog import   Build pricing.yaml from an AWS Price List EC2 offer file
+  catalog lint     Validate the pricing catalog
+  doctor           Check the calculator selectors live or against a snapshot
+  bundle           Zip a run's artifacts into a debug bundle
 `
 
 // main is the entry point for the CLI.
@@ -24,11 +30,46 @@ func main() {
 		return
 	}
 
+	// Grouped commands ("catalog import") take their subcommand as the
+	// second argument.
 	name := os.Args[1]
-	if len(os.Args) > 2 && (os.Args[2] == "--help" || os.Args[2] == "-h") {
-		if name == "map" {
+	rest := os.Args[2:]
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		name += " " + rest[0]
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && (rest[0] == "--help" || rest[0] == "-h") {
+		switch name {
+		case "map":
 			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen map [--params key=value ...]")
 			fmt.Fprintln(os.Stdout, "Creates an AWS Pricing Calculator estimate using MAP.")
+			fmt.Fprintln(os.Stdout, "resume=<run-id> continues a failed run from runs/<run-id>/checkpoint.json.")
+			fmt.Fprintln(os.Stdout, "timeout=20m bounds the browser flow; max_retries=3 retries failed items and timed out runs.")
+			fmt.Fprintln(os.Stdout, "strict_totals=true fails the run when the calculator's totals differ from the plan.")
+			fmt.Fprintln(os.Stdout, "Browser: headful=false chrome_url=ws://... chrome=<path> user_data_dir=... profile=... proxy=... window=1400x1000 no_sandbox=true chrome_flags=a,b=c")
+			fmt.Fprintln(os.Stdout, "Logging: log_file=aws-calculator-gen.log|- log_format=text|json log_level=debug|info|warn|error")
+			return
+		case "plan":
+			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen plan [--params key=value ... export=csv|json out=<file>]")
+			fmt.Fprintln(os.Stdout, "Prints the plan, totals and workplan map would use, without launching Chrome.")
+			fmt.Fprintln(os.Stdout, "export writes the plan as a calculator-style CSV/JSON estimate (to stdout without out).")
+			return
+		case "catalog import":
+			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen catalog import --params file=<offer.json|offer.csv> [regions=... os=... tenancy=... purchase=... out=pricing.yaml]")
+			fmt.Fprintln(os.Stdout, "Converts a locally downloaded AWS Price List EC2 offer file into pricing.yaml (offline).")
+			return
+		case "catalog lint":
+			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen catalog lint [--params file=pricing.yaml strict=true]")
+			fmt.Fprintln(os.Stdout, "Validates a pricing file; exits non-zero when it has errors.")
+			return
+		case "doctor":
+			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen doctor [--params file=tmp.html region=us-east-1 url=... instance=m7g.large headful=true json=true]")
+			fmt.Fprintln(os.Stdout, "Reports which selectors of the selector pack resolve, their node counts and the fallback that wins.")
+			fmt.Fprintln(os.Stdout, "Without file it walks the live calculator up to the share consent; it never shares an estimate.")
+			return
+		case "bundle":
+			fmt.Fprintln(os.Stdout, "Usage: aws-calculator-gen bundle --params run=<run-id> [out=runs/<run-id>.zip]")
+			fmt.Fprintln(os.Stdout, "Zips a run's snapshots, screenshots, log, plan and result, without the browser profile.")
 			return
 		}
 	}
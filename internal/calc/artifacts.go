@@ -0,0 +1,163 @@
+package calc
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ---- Run artifacts: snapshots, screenshots, log, plan and result ----
+
+// runArtifacts writes a run's troubleshooting files to its folder
+// (runs/<run-id>): a numbered HTML snapshot and full-page PNG screenshot per
+// step, run.log, plan.json and result.json.
+type runArtifacts struct {
+	dir string
+	mu  sync.Mutex
+	n   int
+}
+
+// newRunArtifacts returns the artifacts of the run in dir, numbering
+// snapshots after those already there (a resumed run keeps its history).
+func newRunArtifacts(dir string) (*runArtifacts, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	a := &runArtifacts{dir: dir}
+	if m, _ := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9]-*.html")); len(m) > 0 {
+		fmt.Sscanf(filepath.Base(m[len(m)-1]), "%03d-", &a.n)
+	}
+	return a, nil
+}
+
+// logHandler returns a handler appending every entry, debug included, to
+// run.log as text, and the func closing the file.
+func (a *runArtifacts) logHandler() (slog.Handler, func()) {
+	fp, err := os.OpenFile(filepath.Join(a.dir, "run.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return slog.DiscardHandler, func() {}
+	}
+	return slog.NewTextHandler(fp, &slog.HandlerOptions{Level: slog.LevelDebug}), func() { fp.Close() }
+}
+
+// writeJSON writes v to name in the run folder.
+func (a *runArtifacts) writeJSON(name string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(a.dir, name), b, 0o644)
+	}
+	return err
+}
+
+// snapshot writes the page's HTML and a full-page screenshot as the next
+// numbered step, e.g. 004-after-save-add-m7g-large.html/.png, and returns the
+// HTML file.
+func (a *runArtifacts) snapshot(ctx context.Context, note, htmlStr string) (string, error) {
+	a.mu.Lock()
+	a.n++
+	base := fmt.Sprintf("%03d", a.n)
+	a.mu.Unlock()
+	if slug := strings.Trim(runIDUnsafe.ReplaceAllString(strings.ToLower(note), "-"), "-"); slug != "" {
+		base += "-" + slug
+	}
+	path := filepath.Join(a.dir, base+".html")
+	if err := os.WriteFile(path, []byte(htmlStr), 0o644); err != nil {
+		return "", err
+	}
+	// Quality 100 makes chromedp capture a PNG.
+	var png []byte
+	sctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := chromedp.Run(sctx, chromedp.FullScreenshot(&png, 100)); err != nil {
+		logger(ctx).Warn("could not capture screenshot", "note", note, "err", err)
+	} else if err := os.WriteFile(filepath.Join(a.dir, base+".png"), png, 0o644); err != nil {
+		logger(ctx).Warn("could not write screenshot", "note", note, "err", err)
+	}
+	return path, nil
+}
+
+type artifactsKey struct{}
+
+// withArtifacts attaches a run's artifacts to the browser context, where the
+// snapshot helpers find them.
+func withArtifacts(ctx context.Context, a *runArtifacts) context.Context {
+	if a == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, artifactsKey{}, a)
+}
+
+func artifactsFrom(ctx context.Context) *runArtifacts {
+	a, _ := ctx.Value(artifactsKey{}).(*runArtifacts)
+	return a
+}
+
+// WriteRunBundle zips the artifacts of a run in runsDir (default
+// DefaultRunsDir) into out (default runs/<run-id>.zip), to attach to a bug
+// report, and returns the zip's path. The browser profile is left out: it
+// holds the session's cookies.
+func WriteRunBundle(runsDir, runID, out string) (string, error) {
+	if runsDir == "" {
+		runsDir = DefaultRunsDir
+	}
+	if runID == "" || strings.ContainsAny(runID, `/\`) || runID == ".." {
+		return "", fmt.Errorf("invalid run ID %q", runID)
+	}
+	dir := filepath.Join(runsDir, runID)
+	if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+		return "", fmt.Errorf("no run %q in %s", runID, runsDir)
+	}
+	if out == "" {
+		out = dir + ".zip"
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return "", err
+	}
+	zw := zip.NewWriter(f)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if rel == "profile" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		w, err := zw.Create(filepath.ToSlash(filepath.Join(runID, rel)))
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out)
+		return "", err
+	}
+	return out, nil
+}
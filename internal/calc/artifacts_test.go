@@ -0,0 +1,128 @@
+package calc
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRunWritesArtifacts(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{}
+	o := fakeOrchestrator(page)
+	o.RunID = "20261017-120000-acme"
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RunDir != filepath.Join(DefaultRunsDir, o.RunID) {
+		t.Fatalf("run dir = %q", res.RunDir)
+	}
+	var rec struct {
+		ShareURL string
+		RunID    string
+		Items    []PlanItem
+		Error    string
+	}
+	b, err := os.ReadFile(filepath.Join(res.RunDir, "result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ShareURL != res.ShareURL || rec.RunID != o.RunID || len(rec.Items) != 2 || rec.Error != "" {
+		t.Fatalf("unexpected result.json %s", b)
+	}
+	if _, err := os.Stat(filepath.Join(res.RunDir, "plan.json")); err != nil {
+		t.Error(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(res.RunDir, "run.log")); err != nil || !strings.Contains(string(b), "msg=\"estimate shared\"") || !strings.Contains(string(b), res.ShareURL) {
+		t.Errorf("run.log: %v\n%s", err, b)
+	}
+
+	// A failed run records its error.
+	page = &fakePage{fail: map[string]int{"Share": 1}}
+	o = fakeOrchestrator(page)
+	o.RunID = "20261017-120500-acme"
+	if _, err := o.Run(context.Background()); err == nil {
+		t.Fatal("expected the share to fail")
+	}
+	b, _ = os.ReadFile(filepath.Join(DefaultRunsDir, o.RunID, "result.json"))
+	if err := json.Unmarshal(b, &rec); err != nil || !strings.Contains(rec.Error, "Share failed") {
+		t.Fatalf("unexpected result.json %s (%v)", b, err)
+	}
+}
+
+func TestRunArtifactsSnapshotNumbering(t *testing.T) {
+	dir := t.TempDir()
+	a, err := newRunArtifacts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a browser only the HTML is written.
+	for _, note := range []string{"(calculator open)", "(after save/add m7g.large)"} {
+		if _, err := a.snapshot(context.Background(), note, "<html></html>"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A resumed run continues the numbering.
+	a, _ = newRunArtifacts(dir)
+	path, _ := a.snapshot(context.Background(), "(summary)", "<html></html>")
+	if filepath.Base(path) != "003-summary.html" {
+		t.Errorf("snapshot = %s", path)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "002-after-save-add-m7g-large.html")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteRunBundle(t *testing.T) {
+	chdirTemp(t)
+	dir := filepath.Join(DefaultRunsDir, "20261017-120000-acme")
+	for name, body := range map[string]string{
+		"checkpoint.json":          "{}",
+		"run.log":                  "log",
+		"001-calculator-open.html": "<html></html>",
+		"001-calculator-open.png":  "png",
+		"profile/Default/Cookies":  "secret",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := WriteRunBundle("", "20261017-120000-acme", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != dir+".zip" {
+		t.Errorf("bundle = %s", out)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	want := "20261017-120000-acme/001-calculator-open.html,20261017-120000-acme/001-calculator-open.png,20261017-120000-acme/checkpoint.json,20261017-120000-acme/run.log"
+	if strings.Join(names, ",") != want {
+		t.Errorf("bundle has %v", names)
+	}
+
+	if _, err := WriteRunBundle("", "missing", ""); err == nil {
+		t.Error("expected an error for an unknown run")
+	}
+}
@@ -0,0 +1,604 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	awscalculatorgen "github.com/example/aws-calculator-gen"
+)
+
+// ---- Pricing catalog ----
+
+// hoursPerMonth converts hourly prices to monthly ones unless the pricing
+// file (hours_per_month) or the run overrides it.
+const hoursPerMonth = 730.0
+
+// ec2Option is one priced instance type. Hourly and Recurring are the
+// recurring charges, Upfront the one-time fee for the whole commitment term,
+// and Monthly the effective monthly cost (recurring plus amortized upfront)
+// that the planner works with.
+type ec2Option struct {
+	Name      string
+	Hourly    float64
+	Recurring float64
+	Upfront   float64
+	Monthly   float64
+	VCPUs     int
+	MemoryGiB float64
+	OS        string
+	// Hours is the monthly usage the price was computed for (0: 24x7).
+	Hours float64
+}
+
+func (o ec2Option) item(count int) PlanItem {
+	return PlanItem{
+		Name:      o.Name,
+		OS:        o.OS,
+		Hours:     o.Hours,
+		Hourly:    o.Hourly,
+		Recurring: o.Recurring,
+		Upfront:   o.Upfront,
+		Monthly:   o.Monthly,
+		Count:     count,
+		VCPUs:     o.VCPUs,
+		MemoryGiB: o.MemoryGiB,
+	}
+}
+
+// defaultRegion is the region of a legacy flat "ec2" list and of runs that do
+// not name a region.
+const defaultRegion = "us-east-1"
+
+// YAML model
+type pricingEntry struct {
+	Name      string  `yaml:"name"`
+	Hourly    float64 `yaml:"hourly,omitempty"`
+	Monthly   float64 `yaml:"monthly,omitempty"`
+	VCpus     int     `yaml:"vcpus,omitempty"`
+	MemoryGiB float64 `yaml:"memory_gib,omitempty"`
+	// Purchase is a purchase option key (default on-demand); Upfront is the
+	// one-time fee per instance for the whole term.
+	Purchase string  `yaml:"purchase,omitempty"`
+	Upfront  float64 `yaml:"upfront,omitempty"`
+	// OS is an operating system key (default linux).
+	OS string `yaml:"os,omitempty"`
+}
+
+type pricingDoc struct {
+	// Version identifies the prices, e.g. the publication date of the
+	// Price List they were imported from.
+	Version string        `yaml:"version,omitempty"`
+	Filters CatalogFilter `yaml:"filters,omitempty"`
+	// Discounts maps purchase option keys to the average fraction of the
+	// On-Demand cost saved; used for instances without an explicit price.
+	Discounts map[string]float64 `yaml:"discounts,omitempty"`
+	// OSSurcharges maps OS keys to a license fee in USD per vCPU-hour added
+	// to the Linux price of instances without an explicit price for that OS.
+	OSSurcharges map[string]float64 `yaml:"os_surcharges,omitempty"`
+	// HoursPerMonth is the length of a 24x7 month (default 730).
+	HoursPerMonth float64 `yaml:"hours_per_month,omitempty"`
+	// Region names the region of a flat EC2 list (defaults to us-east-1).
+	Region  string                   `yaml:"region,omitempty"`
+	EC2     []pricingEntry           `yaml:"ec2,omitempty"`
+	Regions map[string]pricingRegion `yaml:"regions,omitempty"`
+}
+
+type pricingRegion struct {
+	EC2 []pricingEntry `yaml:"ec2"`
+}
+
+// pricingCatalog is the parsed pricing file: the priced options per region
+// and price key (OS + purchase option), the discounts and license surcharges
+// used to derive missing prices, and the default filter rules declared in its
+// "filters" section.
+type pricingCatalog struct {
+	Info          CatalogInfo
+	Regions       map[string]map[string][]ec2Option
+	Discounts     map[string]float64
+	Surcharges    map[string]float64
+	Filter        CatalogFilter
+	HoursPerMonth float64
+	// Ignored lists the invalid settings and entries left out of the
+	// catalog, for the caller to warn about.
+	Ignored []string
+}
+
+// priceKey identifies a price list inside a region.
+func priceKey(os string, p PurchaseOption) string {
+	if os == "" {
+		os = OSLinux
+	}
+	return os + "/" + p.Key()
+}
+
+// Options returns the options priced for region, operating system os and
+// purchase option p, for months of hpm hours (the catalog's when 0). Missing
+// prices are derived when possible: commitment prices from the On-Demand
+// price and the catalog discount for p, and other operating systems from the
+// Linux price plus the catalog license surcharge.
+func (c pricingCatalog) Options(region, os string, p PurchaseOption, hpm float64) ([]ec2Option, error) {
+	if strings.TrimSpace(region) == "" {
+		region = defaultRegion
+	}
+	if os == "" {
+		os = OSLinux
+	}
+	byKey, ok := c.Regions[region]
+	if !ok {
+		return nil, fmt.Errorf("pricing catalog has no EC2 prices for region %s (available: %s)", region, strings.Join(c.regionCodes(), ", "))
+	}
+	if hpm <= 0 {
+		hpm = c.HoursPerMonth
+	}
+	if hpm <= 0 {
+		hpm = hoursPerMonth
+	}
+	opts := c.derived(byKey, os, p, hpm)
+	if len(opts) == 0 {
+		return nil, fmt.Errorf("pricing catalog has no %s %s prices for region %s (add entries with os: %s, purchase: %s, or a discount/os_surcharge)", OSLabel(os), p.Label(), region, os, p.Key())
+	}
+	sort.Slice(opts, func(i, j int) bool { return opts[i].Monthly < opts[j].Monthly })
+	return opts, nil
+}
+
+func (c pricingCatalog) derived(byKey map[string][]ec2Option, os string, p PurchaseOption, hpm float64) []ec2Option {
+	opts := append([]ec2Option(nil), byKey[priceKey(os, p)]...)
+	have := map[string]bool{}
+	for _, o := range opts {
+		have[o.Name] = true
+	}
+	add := func(o ec2Option) {
+		if !have[o.Name] {
+			have[o.Name] = true
+			o.OS = os
+			opts = append(opts, o)
+		}
+	}
+	if d, ok := c.Discounts[p.Key()]; ok && p.Key() != PurchaseOnDemand {
+		for _, od := range byKey[priceKey(os, PurchaseOption{})] {
+			add(derivePrice(p, od, d, hpm))
+		}
+	}
+	if fee, ok := c.Surcharges[os]; ok && os != OSLinux {
+		for _, lx := range c.derived(byKey, OSLinux, p, hpm) {
+			if lx.VCPUs > 0 && osRunsOn(os, lx.Name) {
+				add(withLicense(lx, fee, hpm))
+			}
+		}
+	}
+	return opts
+}
+
+func (c pricingCatalog) regionCodes() []string {
+	codes := make([]string, 0, len(c.Regions))
+	for r := range c.Regions {
+		codes = append(codes, r)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func (c pricingCatalog) size() int {
+	n := 0
+	for _, byKey := range c.Regions {
+		for _, opts := range byKey {
+			n += len(opts)
+		}
+	}
+	return n
+}
+
+// EmbeddedCatalogSource is the CatalogInfo.Source of the catalog embedded in
+// the binary.
+const EmbeddedCatalogSource = "embedded"
+
+// CatalogInfo identifies the pricing catalog a plan was priced with.
+type CatalogInfo struct {
+	// Source is the pricing file path, or EmbeddedCatalogSource.
+	Source  string
+	Version string
+}
+
+func (i CatalogInfo) String() string {
+	if i.Version == "" {
+		return i.Source + " (unversioned)"
+	}
+	return i.Source + " (version " + i.Version + ")"
+}
+
+// ReadPricing returns the pricing file in use and its source: the file named
+// by EC2_PRICING_YAML, else ./pricing.yaml, else the catalog embedded in the
+// binary. An unreadable EC2_PRICING_YAML is an error, not a fallback.
+func ReadPricing() ([]byte, string, error) {
+	if path := strings.TrimSpace(os.Getenv("EC2_PRICING_YAML")); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, path, fmt.Errorf("could not read pricing file (EC2_PRICING_YAML): %w", err)
+		}
+		return b, path, nil
+	}
+	b, err := os.ReadFile("pricing.yaml")
+	switch {
+	case err == nil:
+		return b, "pricing.yaml", nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, "pricing.yaml", fmt.Errorf("could not read pricing file: %w", err)
+	}
+	return awscalculatorgen.DefaultPricing, EmbeddedCatalogSource, nil
+}
+
+// PricingCatalogInfo reports the source and version of the pricing catalog in
+// use without loading its prices.
+func PricingCatalogInfo() (CatalogInfo, error) {
+	b, source, err := ReadPricing()
+	if err != nil {
+		return CatalogInfo{}, err
+	}
+	doc, err := decodePricingDoc(b)
+	if err != nil {
+		return CatalogInfo{}, fmt.Errorf("invalid pricing file %s (run `aws-calculator-gen catalog lint`): %w", source, err)
+	}
+	return CatalogInfo{Source: source, Version: doc.Version}, nil
+}
+
+// ec2Catalog loads the pricing catalog. Unreadable, malformed or empty files
+// are errors so a broken catalog does not silently produce an empty plan.
+func ec2Catalog() (pricingCatalog, error) {
+	b, source, err := ReadPricing()
+	if err != nil {
+		return pricingCatalog{}, err
+	}
+	doc, err := decodePricingDoc(b)
+	if err != nil {
+		return pricingCatalog{}, fmt.Errorf("invalid pricing file %s (run `aws-calculator-gen catalog lint`): %w", source, err)
+	}
+	cat := catalogFromDoc(doc)
+	cat.Info.Source = source
+	if cat.size() == 0 {
+		return cat, fmt.Errorf("no EC2 entries loaded from %s (run `aws-calculator-gen catalog lint`)", source)
+	}
+	return cat, nil
+}
+
+func decodePricingDoc(b []byte) (pricingDoc, error) {
+	var doc pricingDoc
+	err := yaml.Unmarshal(b, &doc)
+	return doc, err
+}
+
+func parsePricingYAML(b []byte) pricingCatalog {
+	doc, err := decodePricingDoc(b)
+	if err != nil {
+		return pricingCatalog{}
+	}
+	return catalogFromDoc(doc)
+}
+
+// regionLists returns the entries per region code, folding a flat "ec2" list
+// into its region.
+func (doc pricingDoc) regionLists() map[string][]pricingEntry {
+	lists := map[string][]pricingEntry{}
+	if len(doc.EC2) > 0 {
+		region := strings.TrimSpace(doc.Region)
+		if region == "" {
+			region = defaultRegion
+		}
+		lists[region] = append(lists[region], doc.EC2...)
+	}
+	for region, r := range doc.Regions {
+		region = strings.TrimSpace(region)
+		lists[region] = append(lists[region], r.EC2...)
+	}
+	return lists
+}
+
+func catalogFromDoc(doc pricingDoc) pricingCatalog {
+	lists := doc.regionLists()
+
+	// vcpus/memory_gib only need to be declared once per instance type;
+	// entries in other regions inherit them by name.
+	type shape struct {
+		vcpus int
+		mem   float64
+	}
+	shapes := map[string]shape{}
+	for _, list := range lists {
+		for _, it := range list {
+			name := strings.TrimSpace(it.Name)
+			sh := shapes[name]
+			if sh.vcpus == 0 {
+				sh.vcpus = it.VCpus
+			}
+			if sh.mem == 0 {
+				sh.mem = it.MemoryGiB
+			}
+			shapes[name] = sh
+		}
+	}
+
+	cat := pricingCatalog{
+		Regions:    map[string]map[string][]ec2Option{},
+		Discounts:  map[string]float64{},
+		Surcharges: map[string]float64{},
+		Filter:     doc.Filters,
+	}
+	cat.Info.Version = doc.Version
+	cat.HoursPerMonth = doc.HoursPerMonth
+	if cat.HoursPerMonth <= 0 || cat.HoursPerMonth > 744 {
+		if cat.HoursPerMonth != 0 {
+			cat.Ignored = append(cat.Ignored, fmt.Sprintf("hours_per_month %v", doc.HoursPerMonth))
+		}
+		cat.HoursPerMonth = hoursPerMonth
+	}
+	for k, fee := range doc.OSSurcharges {
+		os, err := ParseOperatingSystem(k)
+		if err != nil || fee <= 0 {
+			cat.Ignored = append(cat.Ignored, fmt.Sprintf("os surcharge %q: %v", k, fee))
+			continue
+		}
+		cat.Surcharges[os] = fee
+	}
+	for k, d := range doc.Discounts {
+		p, err := ParsePurchaseOption(k)
+		if err != nil || d <= 0 || d >= 1 {
+			cat.Ignored = append(cat.Ignored, fmt.Sprintf("discount %q: %v", k, d))
+			continue
+		}
+		cat.Discounts[p.Key()] = d
+	}
+	for region, list := range lists {
+		byKey, ignored := parsePricingEntries(list, cat.HoursPerMonth)
+		cat.Ignored = append(cat.Ignored, ignored...)
+		for _, opts := range byKey {
+			for i := range opts {
+				sh := shapes[opts[i].Name]
+				if opts[i].VCPUs == 0 {
+					opts[i].VCPUs = sh.vcpus
+				}
+				if opts[i].MemoryGiB == 0 {
+					opts[i].MemoryGiB = sh.mem
+				}
+			}
+		}
+		if len(byKey) > 0 {
+			cat.Regions[region] = byKey
+		}
+	}
+	return cat
+}
+
+// parsePricingEntries groups entries by price key (OS + purchase option),
+// keeping the cheapest entry per instance type, and returns the entries it
+// ignored.
+func parsePricingEntries(list []pricingEntry, hpm float64) (map[string][]ec2Option, []string) {
+	var ignored []string
+	m := map[string]map[string]ec2Option{}
+	for _, it := range list {
+		name := strings.TrimSpace(it.Name)
+		if name == "" {
+			continue
+		}
+		p, err := ParsePurchaseOption(it.Purchase)
+		if err != nil {
+			ignored = append(ignored, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		os, err := ParseOperatingSystem(it.OS)
+		if err != nil {
+			ignored = append(ignored, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		hr := it.Hourly
+		mo := it.Monthly
+		if mo <= 0 && hr > 0 {
+			mo = hr * hpm
+		}
+		if hr <= 0 && mo > 0 {
+			hr = mo / hpm
+		}
+		if mo <= 0 && it.Upfront <= 0 {
+			continue
+		}
+		o := priceFor(p, name, hr, it.Upfront, hpm)
+		o.VCPUs, o.MemoryGiB, o.OS = it.VCpus, it.MemoryGiB, os
+		key := priceKey(os, p)
+		if m[key] == nil {
+			m[key] = map[string]ec2Option{}
+		}
+		if old, ok := m[key][name]; !ok || o.Monthly < old.Monthly {
+			m[key][name] = o
+		}
+	}
+	out := make(map[string][]ec2Option, len(m))
+	for key, byName := range m {
+		opts := make([]ec2Option, 0, len(byName))
+		for _, v := range byName {
+			opts = append(opts, v)
+		}
+		sort.Slice(opts, func(i, j int) bool { return opts[i].Monthly < opts[j].Monthly })
+		out[key] = opts
+	}
+	return out, ignored
+}
+
+// ---- Instance names and filters ----
+
+// Architectures understood by CatalogFilter.Arch.
+const (
+	ArchARM64 = "arm64"  // AWS Graviton
+	ArchX86   = "x86_64" // Intel and AMD
+)
+
+var instanceNameRe = regexp.MustCompile(`^([a-z]+)(\d+)([a-z-]*)\.([0-9a-z]+)$`)
+
+// instanceName is the decomposition of an EC2 instance type such as
+// "c7gn.2xlarge": series "c", generation 7, attributes "gn", size "2xlarge".
+type instanceName struct {
+	Family     string // series + generation + attributes, e.g. "c7gn"
+	Series     string
+	Generation int
+	Attributes string
+	Size       string
+}
+
+func parseInstanceName(name string) (instanceName, bool) {
+	m := instanceNameRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(name)))
+	if m == nil {
+		return instanceName{}, false
+	}
+	gen, _ := strconv.Atoi(m[2])
+	return instanceName{
+		Family:     m[1] + m[2] + m[3],
+		Series:     m[1],
+		Generation: gen,
+		Attributes: m[3],
+		Size:       m[4],
+	}, true
+}
+
+// Arch returns ArchARM64 for Graviton families ("g" attribute, or a1) and
+// ArchX86 otherwise.
+func (n instanceName) Arch() string {
+	if strings.Contains(n.Attributes, "g") || (n.Series == "a" && n.Generation == 1) {
+		return ArchARM64
+	}
+	return ArchX86
+}
+
+// CatalogFilter selects which catalog entries the planner may use. Family and
+// size rules are glob patterns ("r*", "m7g", "*xlarge"). Empty fields do not
+// restrict anything.
+type CatalogFilter struct {
+	IncludeFamilies []string `yaml:"include_families,omitempty"`
+	ExcludeFamilies []string `yaml:"exclude_families,omitempty"`
+	Generations     []int    `yaml:"generations,omitempty"`
+	MinGeneration   int      `yaml:"min_generation,omitempty"`
+	Arch            string   `yaml:"arch,omitempty"`
+	IncludeSizes    []string `yaml:"include_sizes,omitempty"`
+	ExcludeSizes    []string `yaml:"exclude_sizes,omitempty"`
+}
+
+// NormalizeArch maps user spellings ("graviton", "arm", "x86", "amd64", ...)
+// to ArchARM64 or ArchX86. An empty string stays empty.
+func NormalizeArch(v string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "":
+		return "", nil
+	case "arm64", "arm", "graviton", "aarch64":
+		return ArchARM64, nil
+	case "x86_64", "x86", "amd64", "intel", "amd":
+		return ArchX86, nil
+	default:
+		return "", fmt.Errorf("unknown architecture %q (use graviton or x86)", v)
+	}
+}
+
+// Merge returns f with every non-empty field of o taking precedence.
+func (f CatalogFilter) Merge(o CatalogFilter) CatalogFilter {
+	if len(o.IncludeFamilies) > 0 {
+		f.IncludeFamilies = o.IncludeFamilies
+	}
+	if len(o.ExcludeFamilies) > 0 {
+		f.ExcludeFamilies = o.ExcludeFamilies
+	}
+	if len(o.Generations) > 0 {
+		f.Generations = o.Generations
+	}
+	if o.MinGeneration > 0 {
+		f.MinGeneration = o.MinGeneration
+	}
+	if o.Arch != "" {
+		f.Arch = o.Arch
+	}
+	if len(o.IncludeSizes) > 0 {
+		f.IncludeSizes = o.IncludeSizes
+	}
+	if len(o.ExcludeSizes) > 0 {
+		f.ExcludeSizes = o.ExcludeSizes
+	}
+	return f
+}
+
+// Validate reports malformed patterns and unknown architectures.
+func (f CatalogFilter) Validate() error {
+	if _, err := NormalizeArch(f.Arch); err != nil {
+		return err
+	}
+	for _, list := range [][]string{f.IncludeFamilies, f.ExcludeFamilies, f.IncludeSizes, f.ExcludeSizes} {
+		for _, p := range list {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid filter pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the instance type passes every rule. Names that do
+// not look like an EC2 instance type only pass filters without include,
+// generation or architecture rules.
+func (f CatalogFilter) Allows(name string) bool {
+	n, ok := parseInstanceName(name)
+	if !ok {
+		return len(f.IncludeFamilies) == 0 && len(f.IncludeSizes) == 0 &&
+			len(f.Generations) == 0 && f.MinGeneration == 0 && f.Arch == ""
+	}
+	if len(f.IncludeFamilies) > 0 && !matchAny(f.IncludeFamilies, n.Family) {
+		return false
+	}
+	if matchAny(f.ExcludeFamilies, n.Family) {
+		return false
+	}
+	if len(f.Generations) > 0 && !containsInt(f.Generations, n.Generation) {
+		return false
+	}
+	if n.Generation < f.MinGeneration {
+		return false
+	}
+	if arch, err := NormalizeArch(f.Arch); err == nil && arch != "" && arch != n.Arch() {
+		return false
+	}
+	if len(f.IncludeSizes) > 0 && !matchAny(f.IncludeSizes, n.Size) {
+		return false
+	}
+	return !matchAny(f.ExcludeSizes, n.Size)
+}
+
+// Apply returns the options allowed by the filter, preserving order.
+func (f CatalogFilter) Apply(opts []ec2Option) []ec2Option {
+	out := make([]ec2Option, 0, len(opts))
+	for _, o := range opts {
+		if f.Allows(o.Name) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if ok, err := path.Match(p, s); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package calc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	awscalculatorgen "github.com/example/aws-calculator-gen"
+)
+
+func TestParseInstanceName(t *testing.T) {
+	n, ok := parseInstanceName("c7gn.2xlarge")
+	if !ok || n.Family != "c7gn" || n.Generation != 7 || n.Size != "2xlarge" || n.Arch() != ArchARM64 {
+		t.Fatalf("unexpected parse: %+v ok=%v", n, ok)
+	}
+	if n, _ := parseInstanceName("m6a.large"); n.Arch() != ArchX86 {
+		t.Fatalf("m6a should be x86")
+	}
+	if _, ok := parseInstanceName("not-an-instance"); ok {
+		t.Fatalf("expected parse failure")
+	}
+}
+
+func TestCatalogFilterAllows(t *testing.T) {
+	cases := []struct {
+		f    CatalogFilter
+		name string
+		want bool
+	}{
+		{CatalogFilter{}, "t4g.nano", true},
+		{CatalogFilter{ExcludeSizes: []string{"nano"}}, "t4g.nano", false},
+		{CatalogFilter{IncludeFamilies: []string{"r*"}}, "r8g.large", true},
+		{CatalogFilter{IncludeFamilies: []string{"r*"}}, "m7g.large", false},
+		{CatalogFilter{ExcludeFamilies: []string{"m7g"}}, "m7g.large", false},
+		{CatalogFilter{Arch: "x86"}, "c7g.large", false},
+		{CatalogFilter{Arch: "x86"}, "c6a.8xlarge", true},
+		{CatalogFilter{Arch: "graviton"}, "r8g.4xlarge", true},
+		{CatalogFilter{Generations: []int{6}}, "r7g.large", false},
+		{CatalogFilter{MinGeneration: 7}, "r6g.large", false},
+		{CatalogFilter{IncludeSizes: []string{"*xlarge"}}, "r6g.large", false},
+	}
+	for _, c := range cases {
+		if got := c.f.Allows(c.name); got != c.want {
+			t.Errorf("%+v.Allows(%s) = %v, want %v", c.f, c.name, got, c.want)
+		}
+	}
+}
+
+func TestParsePricingYAMLFilters(t *testing.T) {
+	cat := parsePricingYAML([]byte("filters:\n  exclude_sizes: [nano]\nec2:\n  - name: t4g.nano\n    hourly: 0.0042\n  - name: t4g.medium\n    hourly: 0.0336\n"))
+	opts, err := cat.Options("us-east-1", "", PurchaseOption{}, 0)
+	if err != nil || len(opts) != 2 {
+		t.Fatalf("expected both entries parsed, got %d (%v)", len(opts), err)
+	}
+	kept := cat.Filter.Merge(CatalogFilter{Arch: "graviton"}).Apply(opts)
+	if len(kept) != 1 || kept[0].Name != "t4g.medium" {
+		t.Fatalf("unexpected filtered options: %+v", kept)
+	}
+}
+
+func TestParsePricingYAMLRegions(t *testing.T) {
+	doc := `
+regions:
+  us-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.0816
+        vcpus: 2
+        memory_gib: 8
+  sa-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.1300
+`
+	cat := parsePricingYAML([]byte(doc))
+	sa, err := cat.Options("sa-east-1", "", PurchaseOption{}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sa[0].Hourly != 0.13 || sa[0].VCPUs != 2 || sa[0].MemoryGiB != 8 {
+		t.Fatalf("unexpected sa-east-1 option: %+v", sa[0])
+	}
+	if _, err := cat.Options("eu-west-1", "", PurchaseOption{}, 0); err == nil {
+		t.Fatalf("expected error for region without prices")
+	}
+}
+
+func TestEmbeddedCatalogFallback(t *testing.T) {
+	// The test runs in internal/calc, which has no pricing.yaml.
+	t.Setenv("EC2_PRICING_YAML", "")
+	info, err := PricingCatalogInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Source != EmbeddedCatalogSource || info.Version == "" {
+		t.Fatalf("unexpected catalog info: %+v", info)
+	}
+	cat, err := ec2Catalog()
+	if err != nil || cat.size() == 0 || cat.Info != info {
+		t.Fatalf("embedded catalog not loaded: %d entries, %+v, %v", cat.size(), cat.Info, err)
+	}
+	if issues := LintPricing(awscalculatorgen.DefaultPricing); len(issues) != 0 {
+		t.Errorf("embedded catalog has lint issues: %v", issues)
+	}
+
+	// An explicit EC2_PRICING_YAML never falls back.
+	t.Setenv("EC2_PRICING_YAML", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := ec2Catalog(); err == nil {
+		t.Fatal("expected error for a missing EC2_PRICING_YAML")
+	}
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	if err := os.WriteFile(path, []byte("version: v2\nec2:\n  - {name: m7g.large, hourly: 0.08}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EC2_PRICING_YAML", path)
+	if info, err := PricingCatalogInfo(); err != nil || info.Source != path || info.Version != "v2" {
+		t.Fatalf("unexpected catalog info: %+v, %v", info, err)
+	}
+}
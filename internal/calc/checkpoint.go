@@ -0,0 +1,113 @@
+package calc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ---- Checkpoints: resumable runs ----
+
+// DefaultRunsDir is where runs keep their checkpoint and browser profile,
+// one folder per run ID.
+const DefaultRunsDir = "runs"
+
+// Checkpoint is the progress of a run, saved before the browser opens and
+// after every service added to the estimate. A run that fails can be resumed
+// from it: the browser reopens with the run's profile, where the calculator
+// kept the estimate, and continues after the last saved item. A run whose
+// profile is gone cannot be resumed, nor can a remote run without a remote
+// Chrome.
+type Checkpoint struct {
+	RunID        string         `json:"runId"`
+	EstimateName string         `json:"estimateName"`
+	RegionCode   string         `json:"region"`
+	Purchase     PurchaseOption `json:"purchase"`
+	OS           string         `json:"os,omitempty"`
+	// HoursPerMonth is the 24x7 month the items' usage was planned with.
+	HoursPerMonth float64 `json:"hoursPerMonth"`
+	// Tolerance and StrictTotals are how the calculator's totals are
+	// reconciled with the plan once the estimate is built.
+	Tolerance    float64 `json:"tolerance"`
+	StrictTotals bool    `json:"strictTotals,omitempty"`
+	// Items is the plan; the first Done of them are in the estimate.
+	Items []PlanItem `json:"items"`
+	Done  int        `json:"done"`
+	// ProfileDir is the Chrome user data dir of the run, empty when Remote.
+	ProfileDir string `json:"profileDir"`
+	// Remote is set when the run drives an already running Chrome, which
+	// keeps the estimate in its own profile.
+	Remote bool `json:"remote,omitempty"`
+	// ShareURL is set once the estimate is shared and the run is complete.
+	ShareURL string `json:"shareUrl,omitempty"`
+	// Inputs are the command inputs of the run, so a resume can report the
+	// same deal.
+	Inputs    map[string]string `json:"inputs,omitempty"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+var runIDUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NewRunID returns a run ID made of the current time and name, e.g.
+// "20261017-153045-acme".
+func NewRunID(name string) string {
+	id := time.Now().Format("20060102-150405")
+	if slug := strings.Trim(runIDUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-"); slug != "" {
+		id += "-" + slug
+	}
+	return id
+}
+
+func checkpointPath(runsDir, runID string) string {
+	return filepath.Join(runsDir, runID, "checkpoint.json")
+}
+
+// LoadCheckpoint reads the checkpoint of a run from runsDir (default
+// DefaultRunsDir).
+func LoadCheckpoint(runsDir, runID string) (*Checkpoint, error) {
+	if runsDir == "" {
+		runsDir = DefaultRunsDir
+	}
+	if runID == "" || strings.ContainsAny(runID, `/\`) || runID == ".." {
+		return nil, fmt.Errorf("invalid run ID %q", runID)
+	}
+	b, err := os.ReadFile(checkpointPath(runsDir, runID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no checkpoint for run %q in %s", runID, runsDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint for run %q: %w", runID, err)
+	}
+	if len(cp.Items) == 0 || cp.Done < 0 || cp.Done > len(cp.Items) {
+		return nil, fmt.Errorf("invalid checkpoint for run %q: %d of %d items done", runID, cp.Done, len(cp.Items))
+	}
+	return &cp, nil
+}
+
+// save writes the checkpoint atomically to runsDir.
+func (cp *Checkpoint) save(runsDir string) error {
+	cp.UpdatedAt = time.Now()
+	path := checkpointPath(runsDir, cp.RunID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,180 @@
+package calc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	chdirTemp(t)
+	var profiles []string
+	open := func(page *fakePage) func(context.Context, pageConfig) (CalculatorPage, error) {
+		return func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) {
+			profiles = append(profiles, cfg.browser.UserDataDir)
+			return page, nil
+		}
+	}
+
+	// The second item keeps failing: the first one is checkpointed.
+	page := &fakePage{fail: map[string]int{"SetInstance:c7g.xlarge": 5}}
+	o := fakeOrchestrator(page)
+	o.RunID = "20261017-120000-acme"
+	o.Inputs = map[string]string{"customer": "ACME"}
+	o.openPage = open(page)
+	_, err := o.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "resume with resume=20261017-120000-acme") {
+		t.Fatalf("expected a resumable error, got %v", err)
+	}
+	cp, err := LoadCheckpoint("", "20261017-120000-acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Done != 1 || len(cp.Items) != 2 || cp.ShareURL != "" || cp.Inputs["customer"] != "ACME" || cp.EstimateName != "MAP • ACME" {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+
+	// The estimate lives in the run's profile: without it there is nothing
+	// to resume.
+	wantProfile := filepath.Join(DefaultRunsDir, cp.RunID, "profile")
+	o = Orchestrator{Resume: cp.RunID, openPage: open(&fakePage{})}
+	if _, err := o.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "browser profile") {
+		t.Fatalf("expected a missing profile error, got %v", err)
+	}
+	if err := os.MkdirAll(wantProfile, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resuming skips the saved item, reuses the profile and ignores the
+	// caller's plan.
+	page = &fakePage{}
+	o = Orchestrator{Resume: cp.RunID, MaxRetries: 1, Items: []PlanItem{{Name: "x9.huge", Count: 9}}, openPage: open(page)}
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Open:https://calculator.aws us-east-1",
+		"OpenEC2Configurator", "SetInstance:c7g.xlarge", "SaveAndAdd",
+		"ViewSummary", "ReadSummary", "Rename", "Share",
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Fatalf("calls = %v\nwant    %v", page.calls, want)
+	}
+	if res.RunID != cp.RunID || res.AchievedMRR != 220 || page.name != "MAP • ACME" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if len(profiles) != 2 || profiles[0] != wantProfile || profiles[1] != wantProfile {
+		t.Errorf("profiles = %v, want %s twice", profiles, wantProfile)
+	}
+
+	// A complete run is not built again.
+	cp, _ = LoadCheckpoint("", cp.RunID)
+	if cp.Done != 2 || cp.ShareURL == "" {
+		t.Fatalf("checkpoint not completed: %+v", cp)
+	}
+	page = &fakePage{}
+	o.openPage = open(page)
+	if res, err := o.Run(context.Background()); err != nil || res.ShareURL != cp.ShareURL || len(page.calls) != 0 {
+		t.Errorf("rerun of a complete run: %+v, %v, calls %v", res, err, page.calls)
+	}
+
+	if _, err := LoadCheckpoint("", "nope"); err == nil || !strings.Contains(err.Error(), "no checkpoint") {
+		t.Errorf("expected missing checkpoint error, got %v", err)
+	}
+	if _, err := LoadCheckpoint("", "../x"); err == nil {
+		t.Error("expected invalid run ID error")
+	}
+}
+
+func TestRunResumesRemoteRun(t *testing.T) {
+	chdirTemp(t)
+	remote := BrowserConfig{RemoteURL: "http://127.0.0.1:9222"}
+	var remotes []string
+	open := func(page *fakePage) func(context.Context, pageConfig) (CalculatorPage, error) {
+		return func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) {
+			remotes = append(remotes, cfg.browser.RemoteURL)
+			return page, nil
+		}
+	}
+	page := &fakePage{fail: map[string]int{"SetInstance:c7g.xlarge": 5}}
+	o := fakeOrchestrator(page)
+	o.RunID, o.Browser, o.openPage = "20261017-120000-acme", remote, open(page)
+	if _, err := o.Run(context.Background()); err == nil {
+		t.Fatal("expected the second item to fail")
+	}
+	cp, err := LoadCheckpoint("", o.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cp.Remote || cp.ProfileDir != "" || cp.Done != 1 {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+
+	// The estimate is in the remote Chrome: a local one cannot continue it.
+	o = Orchestrator{Resume: cp.RunID, openPage: open(&fakePage{})}
+	if _, err := o.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "chrome_url") {
+		t.Fatalf("expected a remote Chrome to be required, got %v", err)
+	}
+
+	// No local profile is needed to resume in the remote Chrome.
+	page = &fakePage{}
+	o = Orchestrator{Resume: cp.RunID, Browser: remote, openPage: open(page)}
+	if _, err := o.Run(context.Background()); err != nil {
+		t.Fatalf("resume in the remote Chrome: %v", err)
+	}
+	if n := strings.Count(strings.Join(page.calls, ","), "SetInstance"); n != 1 {
+		t.Errorf("configured %d items on resume, want 1: %v", n, page.calls)
+	}
+	if len(remotes) != 2 || remotes[1] != remote.RemoteURL {
+		t.Errorf("browsers opened on %v", remotes)
+	}
+}
+
+func TestRunResumeKeepsTotalsCheck(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{fail: map[string]int{"SetInstance:c7g.xlarge": 5}}
+	o := fakeOrchestrator(page)
+	o.RunID = "20261017-120000-acme"
+	o.Tolerance, o.StrictTotals = 0.05, true
+	if _, err := o.Run(context.Background()); err == nil {
+		t.Fatal("expected the second item to fail")
+	}
+	cp, err := LoadCheckpoint("", o.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Tolerance != 0.05 || !cp.StrictTotals {
+		t.Fatalf("checkpoint lost the totals check: %+v", cp)
+	}
+	if err := os.MkdirAll(cp.ProfileDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// c7g.xlarge is 4% off: within the saved tolerance, not the default one.
+	resume := func(c7g float64) error {
+		page := &fakePage{summary: &Summary{
+			Services: []SummaryService{{Config: "m7g.large", Monthly: 120}, {Config: "c7g.xlarge", Monthly: c7g}},
+			Monthly:  120 + c7g,
+		}}
+		o := Orchestrator{Resume: cp.RunID, openPage: func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) { return page, nil }}
+		_, err := o.Run(context.Background())
+		return err
+	}
+	if err := resume(110); err == nil || !strings.Contains(err.Error(), "differs from the plan") {
+		t.Fatalf("expected the saved strict_totals to fail the run, got %v", err)
+	}
+	if err := resume(104); err != nil {
+		t.Fatalf("expected the saved tolerance to accept the totals: %v", err)
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	id := NewRunID("MAP • ACME Corp")
+	if len(id) != len("20060102-150405-map-acme-corp") || !strings.HasSuffix(id, "-map-acme-corp") {
+		t.Errorf("NewRunID = %q", id)
+	}
+}
@@ -2,36 +2,116 @@ package calc
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"strings"
 
 	"github.com/chromedp/chromedp"
 )
 
-// newBrowser creates a chromedp context bound to a parent context and optional
-// timeout. The implementation is minimal and intended as a placeholder for a
-// more complete setup with timeouts and flags.
-func newBrowser(parent context.Context, headful bool, timeout time.Duration) (context.Context, context.CancelFunc, error) {
-	opts := []chromedp.ExecAllocatorOption{
-		chromedp.Flag("headless", !headful),
-		chromedp.DisableGPU,
-		chromedp.Flag("no-first-run", true),
-		chromedp.Flag("no-default-browser-check", true),
-	}
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(parent, opts...)
-	ctx, cancelCtx := chromedp.NewContext(allocCtx)
-	if timeout > 0 {
-		var cancelTimeout context.CancelFunc
-		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
-		cancel := func() {
-			cancelTimeout()
-			cancelCtx()
-			cancelAlloc()
+// BrowserConfig is how the browser flow gets its Chrome. The zero value
+// starts the Chrome found on PATH with a temporary profile.
+type BrowserConfig struct {
+	// RemoteURL is the DevTools endpoint of an already running Chrome
+	// (ws://127.0.0.1:9222/devtools/browser/<id>, or http://127.0.0.1:9222)
+	// to drive instead of starting one. The flow opens its own tab there
+	// and leaves the browser running; the other fields are ignored.
+	RemoteURL string
+	// ExecPath is the Chrome binary; empty looks it up on PATH.
+	ExecPath string
+	// UserDataDir is the Chrome user data dir (empty uses a temporary one)
+	// and Profile the profile directory in it, e.g. "Profile 1".
+	UserDataDir string
+	Profile     string
+	// Proxy is the proxy server, e.g. http://proxy:3128 or
+	// socks5://127.0.0.1:1080.
+	Proxy string
+	// WindowWidth and WindowHeight are the window size (default 1400x1000).
+	WindowWidth  int
+	WindowHeight int
+	// NoSandbox disables Chrome's sandbox, which Chrome needs to start as
+	// root in most containers.
+	NoSandbox bool
+	// Flags are extra command line switches without the leading "--", as
+	// "name" or "name=value".
+	Flags []string
+}
+
+// Remote reports whether the config drives an already running Chrome.
+func (c BrowserConfig) Remote() bool { return strings.TrimSpace(c.RemoteURL) != "" }
+
+// flags are the command line switches of a Chrome started for the config;
+// Flags come last and override the defaults.
+func (c BrowserConfig) flags(headful bool) map[string]any {
+	w, h := c.WindowWidth, c.WindowHeight
+	if w <= 0 || h <= 0 {
+		w, h = 1400, 1000
+	}
+	flags := map[string]any{
+		"no-first-run":             true,
+		"no-default-browser-check": true,
+		"headless":                 !headful,
+		"disable-gpu":              true,
+		"disable-dev-shm-usage":    true,
+		"window-size":              fmt.Sprintf("%d,%d", w, h),
+		"disable-blink-features":   "AutomationControlled",
+	}
+	if c.UserDataDir != "" {
+		flags["user-data-dir"] = c.UserDataDir
+	}
+	if c.Profile != "" {
+		flags["profile-directory"] = c.Profile
+	}
+	if c.Proxy != "" {
+		flags["proxy-server"] = c.Proxy
+	}
+	if c.NoSandbox {
+		flags["no-sandbox"] = true
+	}
+	for _, f := range c.Flags {
+		name, value, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(f), "--"), "=")
+		if name == "" {
+			continue
 		}
-		return ctx, cancel, nil
+		if ok {
+			flags[name] = value
+		} else {
+			flags[name] = true
+		}
+	}
+	return flags
+}
+
+// allocatorOptions are the chromedp options of a Chrome started for the
+// config.
+func (c BrowserConfig) allocatorOptions(headful bool) []chromedp.ExecAllocatorOption {
+	var opts []chromedp.ExecAllocatorOption
+	if c.ExecPath != "" {
+		opts = append(opts, chromedp.ExecPath(c.ExecPath))
+	}
+	for name, value := range c.flags(headful) {
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+	return opts
+}
+
+// newBrowser returns the chromedp context of a new tab in the browser cfg
+// describes, starting Chrome (headless unless headful) or connecting to
+// cfg.RemoteURL. cancel closes the tab, and the browser when it was started
+// here. Every browser of the flow and the doctor comes from here.
+func newBrowser(ctx context.Context, headful bool, cfg BrowserConfig) (context.Context, context.CancelFunc) {
+	var allocCtx context.Context
+	var cancelAlloc context.CancelFunc
+	if cfg.Remote() {
+		allocCtx, cancelAlloc = chromedp.NewRemoteAllocator(ctx, strings.TrimSpace(cfg.RemoteURL))
+	} else {
+		allocCtx, cancelAlloc = chromedp.NewExecAllocator(ctx, cfg.allocatorOptions(headful)...)
 	}
-	cancel := func() {
-		cancelCtx()
+	l := logger(ctx).With("component", "chromedp")
+	bctx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(func(format string, args ...interface{}) {
+		l.Debug(fmt.Sprintf(format, args...))
+	}))
+	return bctx, func() {
+		cancelBrowser()
 		cancelAlloc()
 	}
-	return ctx, cancel, nil
 }
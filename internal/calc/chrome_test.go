@@ -0,0 +1,67 @@
+package calc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBrowserConfigFlags(t *testing.T) {
+	flags := BrowserConfig{}.flags(false)
+	if flags["headless"] != true || flags["window-size"] != "1400,1000" || flags["no-sandbox"] != nil || flags["user-data-dir"] != nil {
+		t.Fatalf("unexpected default flags %v", flags)
+	}
+
+	flags = BrowserConfig{
+		UserDataDir:  "/tmp/chrome",
+		Profile:      "Profile 1",
+		Proxy:        "socks5://127.0.0.1:1080",
+		WindowWidth:  1920,
+		WindowHeight: 1080,
+		NoSandbox:    true,
+		Flags:        []string{"--lang=pt-BR", "disable-extensions", "disable-gpu=false"},
+	}.flags(true)
+	want := map[string]any{
+		"headless":           false,
+		"user-data-dir":      "/tmp/chrome",
+		"profile-directory":  "Profile 1",
+		"proxy-server":       "socks5://127.0.0.1:1080",
+		"window-size":        "1920,1080",
+		"no-sandbox":         true,
+		"lang":               "pt-BR",
+		"disable-extensions": true,
+		"disable-gpu":        "false",
+	}
+	for name, v := range want {
+		if flags[name] != v {
+			t.Errorf("%s = %v, want %v", name, flags[name], v)
+		}
+	}
+}
+
+func TestRunUsesBrowserConfig(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{}
+	o := fakeOrchestrator(page)
+	o.Headful = true
+	o.Browser = BrowserConfig{UserDataDir: "/tmp/acme-chrome", NoSandbox: true}
+	var got pageConfig
+	o.openPage = func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) {
+		got = cfg
+		return page, nil
+	}
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.headful || !got.browser.NoSandbox || got.browser.UserDataDir != "/tmp/acme-chrome" {
+		t.Fatalf("page opened with %+v", got)
+	}
+	// The given profile is the one a resume reopens.
+	cp, err := LoadCheckpoint("", res.RunID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.ProfileDir != "/tmp/acme-chrome" {
+		t.Errorf("checkpoint profile = %q", cp.ProfileDir)
+	}
+}
@@ -0,0 +1,346 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// ---- Doctor: selector health check ----
+
+// Doctor checks the selector pack against the calculator: live, walking the
+// flow up to the share consent, or against a saved HTML snapshot.
+type Doctor struct {
+	// BaseURL and RegionCode locate the live calculator (default
+	// DefaultCalculatorURL, us-east-1).
+	BaseURL    string
+	RegionCode string
+	// Snapshot is an HTML snapshot (e.g. tmp.html) to check instead of the
+	// live calculator.
+	Snapshot string
+	Headful  bool
+	// Browser is the Chrome to check with (see BrowserConfig).
+	Browser BrowserConfig
+	// Instance is the instance type looked up in the configurator's table
+	// (default m7g.large).
+	Instance string
+	// Selectors, when set, is the pack checked instead of the one
+	// LoadSelectorPack finds.
+	Selectors *SelectorPack
+	// Logger receives the check's log; nil discards it.
+	Logger *slog.Logger
+
+	// countNodes counts the nodes a selector matches on the page; tests
+	// inject a fake DOM.
+	countNodes func(ctx context.Context, sel selector) (int, error)
+}
+
+// SelectorMatch is how many nodes one selector of a chain matched.
+type SelectorMatch struct {
+	By       string // "css" or "xpath"
+	Selector string
+	Nodes    int
+	// Err is set when the selector could not be evaluated (e.g. invalid
+	// syntax).
+	Err string
+}
+
+// ElementCheck is the health of one logical element at one step of the flow.
+type ElementCheck struct {
+	Step    string
+	Element string
+	Matches []SelectorMatch
+	// Winner is the index of the selector the flow would use, -1 when none
+	// resolves.
+	Winner int
+	// Skipped tells why the element was not checked.
+	Skipped string
+}
+
+// OK reports whether the element resolves.
+func (c ElementCheck) OK() bool { return c.Winner >= 0 }
+
+// DoctorReport is the drift report of a selector pack.
+type DoctorReport struct {
+	// Source is the calculator URL or the snapshot file checked.
+	Source    string
+	Selectors SelectorPackInfo
+	Checks    []ElementCheck
+}
+
+// Unresolved returns the checked elements that no selector matches.
+func (r DoctorReport) Unresolved() []ElementCheck {
+	var out []ElementCheck
+	for _, c := range r.Checks {
+		if c.Skipped == "" && !c.OK() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// doctorStep is a step of the flow: how the live check gets there from the
+// previous step, and the elements the flow needs at that point. Templated
+// elements are checked with the sample text in args.
+type doctorStep struct {
+	name     string
+	enter    func(ctx context.Context, sp *SelectorPack, d *Doctor) error
+	elements []string
+	args     map[string]string
+}
+
+func (d *Doctor) steps() []doctorStep {
+	return []doctorStep{
+		{name: "landing", elements: []string{"create_estimate"}},
+		{
+			name: "service finder",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				dismissCookieBanner(ctx)
+				_ = clickElement(ctx, sp.el("create_estimate"), 5*time.Second)
+				return waitElement(ctx, sp.el("find_service"), 15*time.Second)
+			},
+			elements: []string{"find_service", "view_summary"},
+		},
+		{
+			name: "service search",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				finder, ok := findNow(ctx, sp.el("find_service"))
+				if !ok {
+					return fmt.Errorf("service finder not found")
+				}
+				if err := typeInto(ctx, finder.s, finder.by, "EC2"); err != nil {
+					return err
+				}
+				return waitElement(ctx, sp.el("ec2_configure"), 10*time.Second)
+			},
+			elements: []string{"ec2_configure"},
+		},
+		{
+			name: "EC2 configurator",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				if err := clickElement(ctx, sp.el("ec2_configure"), 10*time.Second); err != nil {
+					return err
+				}
+				dismissCookieBanner(ctx)
+				if err := waitElement(ctx, sp.el("ec2_config_header"), 15*time.Second); err != nil {
+					return err
+				}
+				if search, ok := findNow(ctx, sp.el("instance_search")); ok {
+					_ = typeInto(ctx, search.s, search.by, d.instance())
+					_ = chromedp.Run(ctx, chromedp.Sleep(300*time.Millisecond))
+				}
+				return nil
+			},
+			elements: []string{
+				"ec2_config_header", "instance_count", "any_memory", "any_vcpus",
+				"instance_search", "instance_option", "os_trigger",
+				"usage_unit_trigger", "usage_input", "purchase_option",
+				"app_footer", "save_and_add",
+			},
+			args: map[string]string{"purchase_option": "On-Demand"},
+		},
+		{
+			name: "operating system menu",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				return clickElement(ctx, sp.el("os_trigger"), 5*time.Second)
+			},
+			elements: []string{"os_option"},
+			args:     map[string]string{"os_option": OSLabel(OSLinux)},
+		},
+		{
+			name: "usage unit menu",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				// Close the OS menu on Linux, the calculator default.
+				_ = clickElement(ctx, sp.el("os_option", OSLabel(OSLinux)), 2*time.Second)
+				return clickElement(ctx, sp.el("usage_unit_trigger"), 5*time.Second)
+			},
+			elements: []string{"usage_unit_option"},
+			args:     map[string]string{"usage_unit_option": "Hours/Month"},
+		},
+		{
+			name: "estimate summary",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				_ = chromedp.Run(ctx, chromedp.KeyEvent(kb.Escape))
+				if err := selectInstanceByName(ctx, sp, d.instance(), 5*time.Second); err != nil {
+					return err
+				}
+				if err := clickSaveAndAddService(ctx, sp, DefaultRetryPolicies[StepSaveClick]); err != nil {
+					return err
+				}
+				return clickViewSummary(ctx, sp)
+			},
+			elements: []string{
+				"edit_name", "share_button", "summary_monthly", "summary_upfront",
+				"summary_service_row", "summary_service_monthly",
+			},
+		},
+		{
+			name: "rename",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				return clickElement(ctx, sp.el("edit_name"), 5*time.Second)
+			},
+			elements: []string{"name_input", "save_name"},
+		},
+		{
+			name: "share consent",
+			enter: func(ctx context.Context, sp *SelectorPack, d *Doctor) error {
+				if err := clickElement(ctx, sp.el("share_button"), 5*time.Second); err != nil {
+					return err
+				}
+				return waitElement(ctx, sp.el("share_dialog"), 5*time.Second)
+			},
+			elements: []string{"share_dialog", "share_agree"},
+		},
+		{
+			// Agreeing publishes the estimate, so the live check stops here.
+			name:     "share link",
+			elements: []string{"share_modal_title", "copy_public_link", "share_link_input"},
+		},
+	}
+}
+
+func (d *Doctor) instance() string {
+	if d.Instance != "" {
+		return d.Instance
+	}
+	return "m7g.large"
+}
+
+// Run checks every element of the pack and returns the drift report. A
+// snapshot is a single page, so all elements are checked against it; the
+// live check walks the flow and checks each element where the flow needs it,
+// without publishing anything.
+func (d *Doctor) Run(ctx context.Context) (DoctorReport, error) {
+	if d.Logger != nil {
+		ctx = withLogger(ctx, d.Logger.With("run", "doctor"))
+	}
+	sp := d.Selectors
+	if sp == nil {
+		var err error
+		if sp, err = LoadSelectorPack(); err != nil {
+			return DoctorReport{}, err
+		}
+	}
+	rep := DoctorReport{Selectors: sp.Info}
+
+	bctx, cancel := newBrowser(ctx, d.Headful, d.Browser)
+	defer cancel()
+
+	if d.Snapshot != "" {
+		rep.Source = d.Snapshot
+		if err := loadSnapshot(bctx, d.Snapshot); err != nil {
+			return rep, err
+		}
+		for _, st := range d.steps() {
+			rep.Checks = append(rep.Checks, d.check(bctx, sp, st)...)
+		}
+		return rep, nil
+	}
+
+	region := d.RegionCode
+	if region == "" {
+		region = "us-east-1"
+	}
+	rep.Source = buildURL(calculatorBase(d.BaseURL), region, "")
+	if err := chromedp.Run(bctx, chromedp.Navigate(rep.Source)); err != nil {
+		return rep, err
+	}
+	var stuck string
+	for _, st := range d.steps() {
+		if stuck == "" && st.name == "share link" {
+			stuck = "not checked live: agreeing to share publishes the estimate"
+		}
+		if stuck == "" && st.enter != nil {
+			if err := st.enter(bctx, sp, d); err != nil {
+				stuck = fmt.Sprintf("not reached: %s failed: %v", st.name, err)
+			}
+		}
+		if stuck != "" {
+			for _, name := range st.elements {
+				rep.Checks = append(rep.Checks, ElementCheck{Step: st.name, Element: name, Winner: -1, Skipped: stuck})
+			}
+			continue
+		}
+		rep.Checks = append(rep.Checks, d.check(bctx, sp, st)...)
+	}
+	return rep, nil
+}
+
+// check counts the nodes each selector of the step's elements matches; the
+// winner is the first selector of the chain matching any, as in the flow.
+func (d *Doctor) check(ctx context.Context, sp *SelectorPack, st doctorStep) []ElementCheck {
+	count := d.countNodes
+	if count == nil {
+		count = countNodes
+	}
+	var out []ElementCheck
+	for _, name := range st.elements {
+		text := st.args[name]
+		if name == "instance_option" {
+			text = d.instance()
+		}
+		c := ElementCheck{Step: st.name, Element: name, Winner: -1}
+		for i, sel := range sp.el(name, text).sels {
+			m := SelectorMatch{By: "css", Selector: sel.s}
+			if sel.by == byXPath {
+				m.By = "xpath"
+			}
+			n, err := count(ctx, sel)
+			if err != nil {
+				m.Err = err.Error()
+			}
+			m.Nodes = n
+			if n > 0 && c.Winner < 0 {
+				c.Winner = i
+			}
+			c.Matches = append(c.Matches, m)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// countNodes counts the nodes sel matches in the current document.
+func countNodes(ctx context.Context, sel selector) (int, error) {
+	js := fmt.Sprintf(`document.querySelectorAll(%q).length`, sel.s)
+	if sel.by == byXPath {
+		js = fmt.Sprintf(`document.evaluate(%q, document, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null).snapshotLength`, sel.s)
+	}
+	var n int
+	if err := chromedp.Run(ctx, chromedp.Evaluate(js, &n)); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+var scriptTagRE = regexp.MustCompile(`(?is)<script\b.*?</script>`)
+
+// loadSnapshot shows a saved HTML snapshot in the browser. Its scripts are
+// dropped so the calculator's app does not re-render the saved DOM.
+func loadSnapshot(ctx context.Context, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read snapshot: %w", err)
+	}
+	html := scriptTagRE.ReplaceAllString(string(b), "")
+	return chromedp.Run(ctx,
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			tree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return err
+			}
+			if err := page.SetDocumentContent(tree.Frame.ID, html).Do(ctx); err != nil {
+				return fmt.Errorf("could not load snapshot %s: %w", path, err)
+			}
+			return nil
+		}),
+	)
+}
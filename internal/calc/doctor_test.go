@@ -0,0 +1,70 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDoctorCheck(t *testing.T) {
+	chdirTemp(t)
+	sp, err := LoadSelectorPack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A page where only the second save_and_add fallback and the m7g.large
+	// row resolve, and one selector does not even evaluate.
+	dom := map[string]int{
+		sp.el("save_and_add").sels[1].s:                 1,
+		sp.el("instance_option", "m7g.large").sels[0].s: 2,
+	}
+	d := &Doctor{countNodes: func(ctx context.Context, sel selector) (int, error) {
+		if sel.s == sp.el("app_footer").sels[0].s {
+			return 0, errors.New("SyntaxError")
+		}
+		return dom[sel.s], nil
+	}}
+	var st doctorStep
+	for _, s := range d.steps() {
+		if s.name == "EC2 configurator" {
+			st = s
+		}
+	}
+	checks := d.check(context.Background(), sp, st)
+	got := map[string]ElementCheck{}
+	for _, c := range checks {
+		got[c.Element] = c
+	}
+	if c := got["save_and_add"]; c.Winner != 1 || len(c.Matches) != 3 || c.Matches[1].Nodes != 1 || c.Matches[0].By != "css" {
+		t.Errorf("save_and_add = %+v", c)
+	}
+	if c := got["instance_option"]; c.Winner != 0 || c.Matches[0].Nodes != 2 || !strings.Contains(c.Matches[0].Selector, "m7g.large") {
+		t.Errorf("instance_option = %+v", c)
+	}
+	if c := got["app_footer"]; c.OK() || c.Matches[0].Err != "SyntaxError" {
+		t.Errorf("app_footer = %+v", c)
+	}
+
+	rep := DoctorReport{Checks: append(checks, ElementCheck{Element: "share_agree", Winner: -1, Skipped: "not reached"})}
+	if n := len(rep.Unresolved()); n != len(st.elements)-2 {
+		t.Errorf("unresolved = %d, want %d", n, len(st.elements)-2)
+	}
+}
+
+func TestDoctorStepsCoverPack(t *testing.T) {
+	seen := map[string]bool{}
+	for _, st := range (&Doctor{}).steps() {
+		for _, name := range st.elements {
+			if seen[name] {
+				t.Errorf("element %q checked twice", name)
+			}
+			seen[name] = true
+		}
+	}
+	for _, name := range selectorElements {
+		if !seen[name] {
+			t.Errorf("element %q is not checked by doctor", name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/aws-calculator-gen/internal/calcfixture"
+)
+
+// TestRunAgainstFixture drives the real chromedp flow through headless
+// Chrome against the local calculator stand-in. It needs Chrome on PATH and
+// is skipped otherwise (and with -short).
+func TestRunAgainstFixture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end browser test skipped in -short mode")
+	}
+	if !haveChrome() {
+		t.Skip("Chrome not found on PATH")
+	}
+	chdirTemp(t)
+
+	srv := calcfixture.NewServer(map[string]float64{
+		"m7g.large":  0.0816,
+		"c7g.xlarge": 0.145,
+		"r7g.large":  0.1071,
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	o := Orchestrator{
+		EstimateName: "MAP • ACME",
+		RegionCode:   "us-east-1",
+		MaxRetries:   1,
+		BaseURL:      srv.URL,
+		Items: []PlanItem{
+			{Name: "m7g.large", Count: 2, Recurring: 59.57, Monthly: 59.57},
+			{Name: "c7g.xlarge", Count: 1, Recurring: 105.85, Monthly: 105.85},
+		},
+	}
+	res, err := o.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(res.ShareURL, "/estimate?id=fixture-1") {
+		t.Errorf("share URL = %q", res.ShareURL)
+	}
+	if c := res.Calculator; c == nil || !c.OK() || len(c.Services) != 2 {
+		t.Errorf("calculator totals = %+v", res.Calculator)
+	}
+
+	est := srv.Estimates()
+	if len(est) != 1 {
+		t.Fatalf("got %d shared estimates, want 1", len(est))
+	}
+	if est[0].Name != "MAP • ACME" {
+		t.Errorf("estimate name = %q", est[0].Name)
+	}
+	var got []string
+	for _, s := range est[0].Services {
+		got = append(got, fmt.Sprintf("%s×%d", s.Instance, s.Count))
+	}
+	if strings.Join(got, ",") != "m7g.large×2,c7g.xlarge×1" {
+		t.Errorf("services = %v", got)
+	}
+}
+
+func haveChrome() bool {
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "headless-shell", "chrome"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// TestDoctorAgainstFixture walks the stand-in calculator with the doctor:
+// every element resolves and nothing is shared.
+func TestDoctorAgainstFixture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end-to-end browser test skipped in -short mode")
+	}
+	if !haveChrome() {
+		t.Skip("Chrome not found on PATH")
+	}
+	chdirTemp(t)
+
+	srv := calcfixture.NewServer(map[string]float64{"m7g.large": 0.0816})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	d := &Doctor{BaseURL: srv.URL}
+	rep, err := d.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range rep.Unresolved() {
+		t.Errorf("%s: %s does not resolve", c.Step, c.Element)
+	}
+	for _, c := range rep.Checks {
+		if c.Step == "share link" && c.Skipped == "" {
+			t.Errorf("%s checked live", c.Element)
+		}
+	}
+	if len(srv.Estimates()) != 0 {
+		t.Error("doctor shared an estimate")
+	}
+}
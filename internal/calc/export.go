@@ -0,0 +1,169 @@
+package calc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ---- Offline estimate export (calculator-style CSV/JSON) ----
+
+// EstimateLine is one service row of an exported estimate. Monthly is the
+// recurring cost of the row and Upfront its one-time fees.
+type EstimateLine struct {
+	Group       string
+	Service     string
+	Region      string
+	Description string
+	Config      string
+	Monthly     float64
+	Upfront     float64
+}
+
+// FirstYear is the cost of the row over the first 12 months.
+func (l EstimateLine) FirstYear() float64 { return l.Upfront + 12*l.Monthly }
+
+// Estimate is a plan laid out like the AWS Pricing Calculator's own export,
+// so the numbers can be delivered without the browser flow.
+type Estimate struct {
+	Name      string
+	Currency  string
+	CreatedOn time.Time
+	ShareURL  string
+	Catalog   CatalogInfo
+	Lines     []EstimateLine
+}
+
+// NewEstimate lays out plan items as calculator rows, one per instance type
+// and schedule, grouped under the estimate name (and the schedule, if any).
+func NewEstimate(name, regionCode string, p PurchaseOption, items []PlanItem) Estimate {
+	region, _, _ := strings.Cut(regionLabelFromCode(regionCode), " [")
+	e := Estimate{Name: name, Currency: "USD", CreatedOn: time.Now()}
+	for _, it := range items {
+		group, desc := name, fmt.Sprintf("%d x %s", it.Count, it.Name)
+		if it.Schedule != "" {
+			group += " > " + it.Schedule
+			desc += " (" + it.Schedule + ")"
+		}
+		e.Lines = append(e.Lines, EstimateLine{
+			Group:       group,
+			Service:     "Amazon EC2",
+			Region:      region,
+			Description: desc,
+			Config:      estimateConfig(it, p),
+			Monthly:     float64(it.Count) * it.Recurring,
+			Upfront:     float64(it.Count) * it.Upfront,
+		})
+	}
+	return e
+}
+
+// estimateConfig is the "Configuration summary" of a row, worded like the
+// calculator's.
+func estimateConfig(it PlanItem, p PurchaseOption) string {
+	parts := []string{
+		"Tenancy (Shared Instances)",
+		fmt.Sprintf("Operating system (%s)", OSLabel(it.OS)),
+		fmt.Sprintf("Workload (Consistent, Number of instances: %d)", it.Count),
+		fmt.Sprintf("Advance EC2 instance (%s)", it.Name),
+		fmt.Sprintf("Pricing strategy (%s)", p.Label()),
+	}
+	if it.Hours > 0 {
+		parts = append(parts, fmt.Sprintf("Usage (%g Hours/Month)", it.Hours))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Totals sums the monthly, upfront and first 12 months cost of the estimate.
+func (e Estimate) Totals() (monthly, upfront, firstYear float64) {
+	for _, l := range e.Lines {
+		monthly += l.Monthly
+		upfront += l.Upfront
+		firstYear += l.FirstYear()
+	}
+	return monthly, upfront, firstYear
+}
+
+// WriteCSV writes the estimate in the layout of the calculator's CSV export:
+// an estimate summary followed by the detailed rows.
+func (e Estimate) WriteCSV(w io.Writer) error {
+	monthly, upfront, year := e.Totals()
+	cw := csv.NewWriter(w)
+	rows := [][]string{
+		{"Estimate summary"},
+		{"Upfront cost", "Monthly cost", "Total 12 months cost", "Currency"},
+		{money(upfront), money(monthly), money(year), e.Currency},
+		{"* Includes upfront cost"},
+		{},
+		{"Detailed Estimate"},
+		{"Group hierarchy", "Region", "Description", "Service", "Upfront", "Monthly", "First 12 months total", "Currency", "Status", "Configuration summary"},
+	}
+	for _, l := range e.Lines {
+		rows = append(rows, []string{l.Group, l.Region, l.Description, l.Service, money(l.Upfront), money(l.Monthly), money(l.FirstYear()), e.Currency, "", l.Config})
+	}
+	rows = append(rows,
+		[]string{},
+		[]string{"Acknowledgement"},
+		[]string{estimateDisclaimer},
+		[]string{},
+		[]string{"Estimate name", e.Name},
+		[]string{"Created on", e.CreatedOn.Format(time.RFC3339)},
+		[]string{"Pricing catalog", e.Catalog.String()},
+	)
+	if e.ShareURL != "" {
+		rows = append(rows, []string{"Share URL", e.ShareURL})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteJSON writes the estimate in the layout of the calculator's JSON
+// export.
+func (e Estimate) WriteJSON(w io.Writer) error {
+	cost := func(monthly, upfront, year float64) map[string]any {
+		return map[string]any{"monthly": round2(monthly), "upfront": round2(upfront), "12 months": round2(year)}
+	}
+	services := make([]map[string]any, 0, len(e.Lines))
+	for _, l := range e.Lines {
+		services = append(services, map[string]any{
+			"Group":                 l.Group,
+			"Service Name":          l.Service,
+			"Region":                l.Region,
+			"Description":           l.Description,
+			"Configuration Summary": l.Config,
+			"Service Cost":          cost(l.Monthly, l.Upfront, l.FirstYear()),
+		})
+	}
+	metadata := map[string]any{
+		"Currency":         e.Currency,
+		"Locale":           "en_US",
+		"Created On":       e.CreatedOn.Format(time.RFC3339),
+		"Legal Disclaimer": estimateDisclaimer,
+		"Pricing Catalog":  map[string]any{"Source": e.Catalog.Source, "Version": e.Catalog.Version},
+	}
+	if e.ShareURL != "" {
+		metadata["Share Url"] = e.ShareURL
+	}
+	doc := map[string]any{
+		"Name":       e.Name,
+		"Total Cost": cost(e.Totals()),
+		"Metadata":   metadata,
+		"Groups":     map[string]any{"Services": services},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+const estimateDisclaimer = "AWS Pricing Calculator provides only an estimate of your AWS fees and doesn't include any taxes that might apply. Your actual fees depend on a variety of factors, including your actual usage of AWS services. This estimate was generated offline from the pricing catalog, without the calculator."
+
+func money(v float64) string { return fmt.Sprintf("%.2f", v) }
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
@@ -0,0 +1,86 @@
+package calc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testEstimate(t *testing.T) Estimate {
+	t.Helper()
+	ri, err := ParsePurchaseOption("ri-1yr-partial-upfront")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []PlanItem{
+		{Name: "m7g.large", OS: OSLinux, Count: 4, Recurring: 30, Upfront: 240, Monthly: 50},
+		{Name: "c7g.large", OS: OSLinux, Count: 2, Recurring: 10, Monthly: 10, Schedule: "dev", Hours: 176},
+	}
+	e := NewEstimate("MAP • ACME", "us-east-1", ri, items)
+	e.Catalog = CatalogInfo{Source: EmbeddedCatalogSource, Version: "v1"}
+	return e
+}
+
+func TestNewEstimate(t *testing.T) {
+	e := testEstimate(t)
+	if len(e.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %+v", e.Lines)
+	}
+	l := e.Lines[0]
+	if l.Region != "US East (N. Virginia)" || l.Monthly != 120 || l.Upfront != 960 || l.FirstYear() != 960+12*120 {
+		t.Errorf("unexpected line %+v", l)
+	}
+	if e.Lines[1].Group != "MAP • ACME > dev" || !strings.Contains(e.Lines[1].Config, "Usage (176 Hours/Month)") {
+		t.Errorf("schedule not reflected: %+v", e.Lines[1])
+	}
+	monthly, upfront, year := e.Totals()
+	if monthly != 140 || upfront != 960 || year != 960+12*140 {
+		t.Errorf("totals = %v, %v, %v", monthly, upfront, year)
+	}
+}
+
+func TestEstimateWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testEstimate(t).WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(&buf)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[2][0] != "960.00" || rows[2][1] != "140.00" || rows[2][2] != "2640.00" {
+		t.Errorf("unexpected summary %v", rows[2])
+	}
+	// The CSV reader skips the blank separator lines.
+	if rows[4][0] != "Detailed Estimate" || rows[5][0] != "Group hierarchy" || rows[6][2] != "4 x m7g.large" || rows[6][6] != "2400.00" {
+		t.Errorf("unexpected detail rows %v", rows[4:7])
+	}
+}
+
+func TestEstimateWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testEstimate(t).WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Name      string
+		TotalCost map[string]float64 `json:"Total Cost"`
+		Metadata  map[string]any
+		Groups    struct {
+			Services []map[string]any
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Name != "MAP • ACME" || doc.TotalCost["monthly"] != 140 || doc.TotalCost["12 months"] != 2640 || len(doc.Groups.Services) != 2 {
+		t.Fatalf("unexpected export: %s", buf.String())
+	}
+	if doc.Metadata["Currency"] != "USD" || doc.Groups.Services[0]["Service Name"] != "Amazon EC2" {
+		t.Errorf("unexpected metadata or service: %s", buf.String())
+	}
+}
@@ -4,17 +4,15 @@ import (
 	"context"
 	"fmt"
 	"html"
-	"log"
+	"log/slog"
 	"math"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 	"time"
 
-	yaml "gopkg.in/yaml.v3"
-
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/chromedp"
 	"github.com/chromedp/chromedp/kb"
@@ -25,9 +23,61 @@ type Orchestrator struct {
 	RegionCode   string
 	TargetMRR    float64
 	Headful      bool
-	Tolerance    float64
-	Timeout      time.Duration
-	MaxRetries   int
+	// Browser configures the Chrome the flow starts or connects to. Its
+	// UserDataDir, when set, replaces the run's own profile.
+	Browser   BrowserConfig
+	Tolerance float64
+	// Timeout is the deadline of the browser flow, retries included; zero
+	// means none.
+	Timeout time.Duration
+	// MaxRetries is how many times a failed item is configured again, and
+	// a run that failed transiently is tried again in a fresh browser.
+	MaxRetries  int
+	Constraints PlanConstraints
+	Filter      CatalogFilter
+	// Workload, when set, sizes the plan by capacity instead of TargetMRR.
+	Workload *WorkloadTarget
+	Purchase PurchaseOption
+	// OS is an operating system key (see ParseOperatingSystem); empty is Linux.
+	OS string
+	// HoursPerMonth overrides the catalog's 24x7 month (default 730 hours).
+	HoursPerMonth float64
+	// Schedules split the target between usage schedules (e.g. prod 24x7
+	// and dev 8x22); empty means everything runs 24x7.
+	Schedules []Schedule
+	// Items, when set, is a preselected plan (e.g. one of Plans) used instead
+	// of running the planner.
+	Items []PlanItem
+	// BaseURL is where the calculator is served (default
+	// DefaultCalculatorURL); tests point it at a local fixture server.
+	BaseURL string
+	// Selectors, when set, is the selector pack used instead of the one
+	// LoadSelectorPack finds.
+	Selectors *SelectorPack
+	// RunID names the run's folder under RunsDir (default DefaultRunsDir),
+	// which holds its checkpoint and browser profile; empty generates one
+	// with NewRunID.
+	RunID   string
+	RunsDir string
+	// Resume, when set, is the ID of a failed run to continue from its last
+	// checkpoint instead of planning a new estimate.
+	Resume string
+	// Inputs are saved with the checkpoint (see Checkpoint.Inputs).
+	Inputs map[string]string
+	// Logger receives the run's structured log; every entry carries the run
+	// ID and, in the browser flow, the step. Nil discards it, though the run
+	// folder's run.log is still written.
+	Logger *slog.Logger
+	// Retry overrides DefaultRetryPolicies by step.
+	Retry map[string]RetryPolicy
+	// StrictTotals fails the run, before sharing, when the calculator's
+	// summary cannot be read or differs from the plan by more than
+	// Tolerance; otherwise the differences are logged as warnings.
+	StrictTotals bool
+
+	// openPage opens the calculator UI; nil launches Chrome. Tests inject a
+	// fake page.
+	openPage func(ctx context.Context, cfg pageConfig) (CalculatorPage, error)
 }
 
 type Result struct {
@@ -37,253 +87,378 @@ type Result struct {
 	Count         int
 	AchievedMRR   float64
 	RelativeError float64
+	Items         []PlanItem
+	Upfront       float64
+	Purchase      string
+	// Catalog is the pricing catalog the plan was priced with.
+	Catalog CatalogInfo
+	// Selectors is the selector pack the browser flow used, RunID the run's
+	// checkpoint and RunDir its folder of artifacts (Run only).
+	Selectors SelectorPackInfo
+	RunID     string
+	RunDir    string
+	// Calculator is the estimate summary as the calculator computed it,
+	// reconciled with the plan (Run only; nil when it could not be read).
+	Calculator *Reconciliation
 }
 
-// ---- Selectors ----
-const (
-	createEstimateBtnXPath    = `//button[.//span[normalize-space()='Create estimate' or normalize-space()='Create Estimate']] | //span[normalize-space()='Create estimate' or normalize-space()='Create Estimate']/ancestor::button`
-	findServiceInputCSS       = `input[aria-label="Find Service"]`
-	ec2ConfigureXPath         = `//*[@data-cy="Amazon EC2 -button"]//button | //button[.//span[contains(normalize-space(.),'Configure Amazon EC2')]]`
-	ec2ConfigHeaderXPath      = `//h1[contains(normalize-space(),'Configure Amazon EC2')]`
-	numberInstancesInputXPath = `
-		(
-		  //input[contains(translate(@aria-label,'ABCDEFGHIJKLMNOPQRSTUVWXYZ','abcdefghijklmnopqrstuvwxyz'),'number of instances')]
-		)[1]`
-	instanceSearchInputCSS       = `input[aria-label*="Search instance types"], input[placeholder*="Search instance types"], input[aria-label*="Search by instance name"]`
-	anyMemoryTriggerXPath        = `//*[@id='ec2enhancement']//*[contains(normalize-space(),'Any Memory')]/ancestor::*[self::button or self::div[contains(@class,'trigger')]] | //*[contains(@id,'trigger-content') and contains(normalize-space(),'Any Memory')]`
-	anyVcpuTriggerXPath          = `//*[@id='ec2enhancement']//*[contains(normalize-space(),'Any vCPUs')]/ancestor::*[self::button or self::div[contains(@class,'trigger')]] | //*[contains(@id,'trigger-content') and contains(normalize-space(),'Any vCPUs')]`
-	onDemandOptionXPath          = `//label[.//text()[contains(.,'On-Demand')]] | //input[@type='radio' and (contains(@value,'On-Demand') or contains(@aria-label,'On-Demand'))]/ancestor::label`
-	appFooterCSS                 = `.appFooter`
-	saveAndAddXPath              = `//button[@data-cy='Save and add service-button' and not(@disabled)] | //button[.//span[normalize-space()='Save and add service'] and not(@disabled)]`
-	saveAndAddBtnFooterCSS       = `div.appFooter [data-cy='Save and add service-button']`
-	viewSummaryBtnCSS            = `#estimate-button`
-	editNameLinkCSS              = `a[data-cy="edit-estimate-name"]`
-	editNameLinkXPath            = `//*[@data-cy='edit-estimate-name'] | //*[normalize-space()='Edit' and (self::button or self::span or self::a)]/ancestor::a | //*[contains(@class,'myEstimate')]//*[normalize-space()='Edit']`
-	nameInputCSS                 = `input[aria-label="Enter Name"]`
-	saveNameBtnXPath             = `//button[.//span[normalize-space()='Save'] and not(@disabled)]`
-	numberInstancesInputCSSExact = `input[aria-label="Number of instances Enter amount"]`
-
-	// Share
-	shareBtnXPath            = `//*[@data-cy='save-and-share'] | //button[.//span[contains(normalize-space(.),'Share')]]`
-	shareConsentDialogXPath  = `//div[@role='dialog' or contains(@class,'awsui-modal-root') or contains(@class,'awsui_modal-root')]`
-	shareModalTitleXPath     = `(` + shareConsentDialogXPath + `)//*[self::h1 or self::h2][normalize-space()='Save estimate']`
-	copyPublicLinkXPath      = `//button[.//span[normalize-space()='Copy public link']]`
-	shareLinkInputCSS        = `div.clipboard-inputfield > input`
-	shareLinkInputXPath      = `//div[contains(@class,'clipboard-inputfield')]//input | //input[@aria-label='Copy public link' or @aria-label='Public share link'] | //input[contains(@value,'calculator.aws') and contains(@value,'/estimate')]`
-	shareAgreeContinueBtnCSS = `button[data-id="agree-continue"], button[aria-label="Agree and continue"], button[title="Agree and continue"]`
-)
+// DefaultCalculatorURL is the public AWS Pricing Calculator.
+const DefaultCalculatorURL = "https://calculator.aws"
 
-func (o *Orchestrator) Run(ctx context.Context) (Result, error) {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+func (o *Orchestrator) baseURL() string { return calculatorBase(o.BaseURL) }
 
-	if fp, err := os.OpenFile("aws-calculator-gen.log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err == nil {
-		log.SetOutput(fp)
-		defer func(fp *os.File) {
-			err := fp.Close()
-			if err != nil {
-				log.Printf("Error: %s", err)
-			}
-		}(fp)
-	}
-
-	// 1) Launch Chrome
-	log.Printf("[1/10] Launching Chrome (headful=%v)...", o.Headful)
-	allocOpts := []chromedp.ExecAllocatorOption{
-		chromedp.NoFirstRun,
-		chromedp.NoDefaultBrowserCheck,
-		chromedp.Flag("headless", !o.Headful),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("disable-dev-shm-usage", true),
-		chromedp.Flag("window-size", "1400,1000"),
-		chromedp.Flag("disable-blink-features", "AutomationControlled"),
-	}
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, allocOpts...)
-	defer cancelAlloc()
-	bctx, cancelBrowser := chromedp.NewContext(allocCtx, chromedp.WithLogf(func(format string, args ...interface{}) {
-		log.Printf("[chromedp] "+format, args...)
-	}))
-	defer cancelBrowser()
-	defer dumpHTML(bctx, "(always end)")
-
-	// 2) Navigate
-	base := "https://calculator.aws"
-	navURL := buildURL(base, o.RegionCode, "")
-	log.Printf("[2/10] Navigating to %s ...", navURL)
-	if err := chromedp.Run(bctx, chromedp.Navigate(navURL)); err != nil {
-		return Result{}, err
+// calculatorBase is base without a trailing slash, or DefaultCalculatorURL
+// when empty.
+func calculatorBase(base string) string {
+	if b := strings.TrimRight(strings.TrimSpace(base), "/"); b != "" {
+		return b
 	}
-	dismissCookieBanner(bctx)
+	return DefaultCalculatorURL
+}
+
+// logger is the orchestrator's Logger, or one discarding everything.
+func (o *Orchestrator) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return discardLogger
+}
 
-	// 3) Optional "Create estimate"
-	log.Printf("[3/10] Trying optional 'Create estimate' click if present...")
-	_ = clickAny(bctx, []selector{{s: createEstimateBtnXPath, by: byXPath}})
-	log.Printf("        Current URL: %s", currentURL(bctx))
+// Plan runs the planner only, without launching Chrome, and returns the
+// result Run would report minus the share link.
+func (o *Orchestrator) Plan() (Result, error) {
+	return o.planResult()
+}
 
-	// 4) Ensure "Find Service"
-	log.Printf("[4/10] Ensuring 'Find Service' input...")
-	if err := chromedp.Run(bctx, chromedp.WaitVisible(findServiceInputCSS, chromedp.ByQuery)); err != nil {
+func (o *Orchestrator) planResult() (Result, error) {
+	plan, err := o.plan()
+	if err != nil {
+		o.logger().Error("planning failed", "step", "plan", "err", err)
 		return Result{}, err
 	}
+	if len(plan) == 0 {
+		return Result{}, fmt.Errorf("the plan has no items; refusing to open an empty estimate")
+	}
+	catalog, err := PricingCatalogInfo()
+	if err != nil {
+		return Result{}, err
+	}
+	total := planTotal(plan)
+	relErr := 0.0
+	if o.TargetMRR > 0 {
+		relErr = math.Abs(total-o.TargetMRR) / o.TargetMRR
+	}
+	return Result{
+		RegionLabel:   regionLabelFromCode(o.RegionCode),
+		AchievedMRR:   total,
+		RelativeError: relErr,
+		Items:         plan,
+		Upfront:       planUpfront(plan),
+		Purchase:      o.Purchase.Label(),
+		Catalog:       catalog,
+	}, nil
+}
 
-	// 5) Type EC2
-	log.Printf("[5/10] Typing 'EC2' into service finder...")
-	if err := typeInto(bctx, findServiceInputCSS, byCSS, "EC2"); err != nil {
-		log.Printf("       typing failed, JS-fallback...")
-		if err2 := setInputValueJS(bctx, findServiceInputCSS, byCSS, "EC2"); err2 != nil {
-			return Result{}, fmt.Errorf("cannot type 'EC2': %v / fb: %v", err, err2)
-		}
-		_ = chromedp.Run(bctx, chromedp.SendKeys(findServiceInputCSS, kb.Enter, chromedp.ByQuery))
+// hoursPerMonth is the run's 24x7 month for the browser flow.
+func (o *Orchestrator) hoursPerMonth() float64 {
+	if o.HoursPerMonth > 0 {
+		return o.HoursPerMonth
 	}
+	return hoursPerMonth
+}
 
-	// 6) Click "Configure Amazon EC2"
-	log.Printf("[6/10] Selecting 'Configure Amazon EC2'...")
-	if !clickAny(bctx, []selector{{s: ec2ConfigureXPath, by: byXPath}}) {
-		_ = chromedp.Run(bctx, chromedp.SendKeys(findServiceInputCSS, kb.Enter, chromedp.ByQuery))
-		if !clickAny(bctx, []selector{{s: ec2ConfigureXPath, by: byXPath}}) {
-			return Result{}, fmt.Errorf("could not find 'Configure Amazon EC2'")
+// Run plans the estimate and builds it in the calculator: one EC2 service
+// per plan item, then the summary, the name and the public share link. The
+// progress is checkpointed after every item; a failed run can be continued
+// with Resume.
+func (o *Orchestrator) Run(ctx context.Context) (Result, error) {
+	runsDir := o.RunsDir
+	if runsDir == "" {
+		runsDir = DefaultRunsDir
+	}
+	var cp *Checkpoint
+	runID := o.RunID
+	if o.Resume != "" {
+		var err error
+		if cp, err = LoadCheckpoint(runsDir, o.Resume); err != nil {
+			return Result{}, err
 		}
-	}
-	log.Printf("        Current URL after configure: %s", currentURL(bctx))
-	dismissCookieBanner(bctx)
-	_ = waitVisibleWithTimeout(bctx, ec2ConfigHeaderXPath, byXPath, 5*time.Second)
-	_ = waitVisibleWithTimeout(bctx, numberInstancesInputXPath, byXPath, 5*time.Second)
-
-	// ---- PLANO (greedy desc, ignorando *.nano) ----
-	plan := planGreedyEC2(o.TargetMRR /*mrr*/, o.Tolerance)
-	totalApprox := 0.0
-	for _, it := range plan {
-		totalApprox += float64(it.Count) * it.Monthly
-	}
-
-	log.Printf("        PLAN (greedy desc, no nano) — target MRR=%.2f:", o.TargetMRR)
-	for _, it := range plan {
-		log.Printf("          - %d x %s  (~$%.2f/mo cada, ~$%.2f total)", it.Count, it.Name, it.Monthly, float64(it.Count)*it.Monthly)
-	}
-	log.Printf("        PLAN total ~= $%.2f/mo", totalApprox)
-
-	for idx, it := range plan {
-		// If not the first item, reopen the EC2 configurator
-		if idx > 0 {
-			_ = waitVisibleWithTimeout(bctx, findServiceInputCSS, byCSS, 5*time.Second)
-			_ = typeInto(bctx, findServiceInputCSS, byCSS, "EC2")
-			_ = chromedp.Run(bctx, chromedp.Sleep(200*time.Millisecond))
-			if !clickAny(bctx, []selector{{s: ec2ConfigureXPath, by: byXPath}}) {
-				_ = chromedp.Run(bctx, chromedp.SendKeys(findServiceInputCSS, kb.Enter, chromedp.ByQuery))
-				if !clickAny(bctx, []selector{{s: ec2ConfigureXPath, by: byXPath}}) {
-					return Result{}, fmt.Errorf("could not re-open 'Configure Amazon EC2' for planned item %d", idx+1)
+		// The calculator restores the estimate from the run's profile only;
+		// without it the services added so far would be lost. A remote
+		// Chrome keeps its own profile, out of reach.
+		if cp.Done > 0 && cp.ShareURL == "" {
+			if cp.Remote {
+				if !o.Browser.Remote() {
+					return Result{}, fmt.Errorf("cannot resume run %q without chrome_url: it was built in a remote Chrome", cp.RunID)
 				}
+			} else if _, err := os.Stat(cp.ProfileDir); err != nil {
+				return Result{}, fmt.Errorf("cannot resume run %q: its browser profile %s is gone, start a new run", cp.RunID, cp.ProfileDir)
 			}
-			_ = waitVisibleWithTimeout(bctx, ec2ConfigHeaderXPath, byXPath, 5*time.Second)
-			_ = waitVisibleWithTimeout(bctx, numberInstancesInputXPath, byXPath, 5*time.Second)
-			dismissCookieBanner(bctx)
 		}
+		runID = cp.RunID
+		resumed := *o
+		resumed.EstimateName, resumed.RegionCode = cp.EstimateName, cp.RegionCode
+		resumed.Purchase, resumed.OS, resumed.HoursPerMonth = cp.Purchase, cp.OS, cp.HoursPerMonth
+		resumed.Tolerance, resumed.StrictTotals = cp.Tolerance, cp.StrictTotals
+		resumed.Items = cp.Items
+		o = &resumed
+	}
+	if runID == "" {
+		runID = NewRunID(o.EstimateName)
+	}
+	runDir := filepath.Join(runsDir, runID)
+	art, err := newRunArtifacts(runDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("could not create the run folder: %w", err)
+	}
+	runLog, closeLog := art.logHandler()
+	defer closeLog()
+	l := slog.New(teeHandler{o.logger().Handler(), runLog}).With("run", runID)
+	ctx = withLogger(ctx, l)
+	logged := *o
+	logged.Logger = l
+	o = &logged
+	record := func(name string, v any) {
+		if err := art.writeJSON(name, v); err != nil {
+			l.Warn("could not write run artifact", "file", name, "err", err)
+		}
+	}
+	if cp != nil {
+		l.Info("resuming run", "done", cp.Done, "items", len(cp.Items))
+	}
 
-		// Filters/price model
-		_ = ensureAnyFilters(bctx, 5*time.Second)
-		_ = ensureOnDemand(bctx, 5*time.Second)
-
-		if err := setInstanceCount(bctx, it.Count); err != nil {
-			dumpHTML(bctx, fmt.Sprintf("(set count %s failed)", it.Name))
-			return Result{}, fmt.Errorf("could not set count for %q: %w", it.Name, err)
+	// 0) Plan before touching the browser so an impossible target fails fast
+	res, err := o.planResult()
+	if err != nil {
+		record("result.json", runRecord{Error: err.Error()})
+		return Result{}, err
+	}
+	if cp == nil {
+		cp = &Checkpoint{
+			RunID:         runID,
+			EstimateName:  o.EstimateName,
+			RegionCode:    o.RegionCode,
+			Purchase:      o.Purchase,
+			OS:            o.OS,
+			HoursPerMonth: o.HoursPerMonth,
+			Tolerance:     o.Tolerance,
+			StrictTotals:  o.StrictTotals,
+			Items:         res.Items,
+			ProfileDir:    o.Browser.UserDataDir,
+			Remote:        o.Browser.Remote(),
+			Inputs:        o.Inputs,
+		}
+		if cp.Remote {
+			cp.ProfileDir = ""
+		} else if cp.ProfileDir == "" {
+			cp.ProfileDir = filepath.Join(runsDir, runID, "profile")
+		}
+		if err := cp.save(runsDir); err != nil {
+			return Result{}, fmt.Errorf("could not save checkpoint: %w", err)
 		}
+	}
+	res.RunID, res.RunDir = runID, runDir
+	record("plan.json", res)
+	if cp.ShareURL != "" {
+		l.Info("run already complete", "share_url", cp.ShareURL)
+		res.ShareURL = cp.ShareURL
+		record("result.json", runRecord{Result: res})
+		return res, nil
+	}
+
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	policies := retryPolicies(o.Retry, o.MaxRetries)
+	planned := res
+	// Every attempt opens a fresh browser on the run's profile and continues
+	// after the last checkpointed item.
+	err = policies[StepRun].do(ctx, StepRun, transient, func(ctx context.Context) error {
+		built, err := o.build(ctx, planned, cp, runsDir, policies, art)
+		if err == nil {
+			res = built
+		}
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("%w (resume with resume=%s)", err, cp.RunID)
+		record("result.json", runRecord{Result: res, Error: err.Error()})
+		return Result{}, err
+	}
+	record("result.json", runRecord{Result: res})
+	return res, nil
+}
+
+// runRecord is the result.json of a run: its result, or the error it failed
+// with.
+type runRecord struct {
+	Result
+	Error string `json:",omitempty"`
+}
+
+// runStep runs one step of the browser flow under its retry policy, logging
+// its outcome and duration.
+func runStep(ctx context.Context, policies map[string]RetryPolicy, step string, fn func(ctx context.Context) error) error {
+	l := logger(ctx).With("step", step)
+	start := time.Now()
+	err := policies[step].do(withLogger(ctx, l), step, nil, fn)
+	if err != nil {
+		l.Error("step failed", "duration", time.Since(start), "err", err)
+	} else {
+		l.Info("step done", "duration", time.Since(start))
+	}
+	return err
+}
+
+// build runs the browser flow for the items not done yet in cp, saving cp
+// after every item added and once shared.
+func (o *Orchestrator) build(ctx context.Context, res Result, cp *Checkpoint, runsDir string, policies map[string]RetryPolicy, art *runArtifacts) (Result, error) {
+	var err error
 
-		// Select instance by exact name, then set instance count
-		if err := selectInstanceByName(bctx, it.Name, 5*time.Second); err != nil {
-			dumpHTML(bctx, fmt.Sprintf("(select %s failed)", it.Name))
-			return Result{}, fmt.Errorf("could not select instance %q: %w", it.Name, err)
+	sp := o.Selectors
+	if sp == nil {
+		if sp, err = LoadSelectorPack(); err != nil {
+			return Result{}, err
 		}
+	}
+	res.Selectors = sp.Info
+	l := logger(ctx)
+	l.Info("selector pack", "source", sp.Info.Source, "version", sp.Info.Version)
 
-		// Save and add service, then continue to the next planned item
-		if err := clickSaveAndAddService(bctx); err != nil {
-			dumpHTML(bctx, fmt.Sprintf("(save/add %s failed)", it.Name))
+	// 1-4) Launch Chrome and open the calculator
+	openPage := o.openPage
+	if openPage == nil {
+		openPage = newChromePage
+	}
+	browser := o.Browser
+	browser.UserDataDir = cp.ProfileDir
+	page, err := openPage(ctx, pageConfig{headful: o.Headful, browser: browser, selectors: sp, retry: policies, artifacts: art})
+	if err != nil {
+		return Result{}, &StepError{Step: StepOpen, Attempts: 1, Err: err}
+	}
+	defer page.Close()
+	if err := runStep(ctx, policies, StepOpen, func(ctx context.Context) error {
+		return page.Open(ctx, o.baseURL(), o.RegionCode)
+	}); err != nil {
+		page.Snapshot("(open failed)")
+		return Result{}, err
+	}
+	page.Snapshot("(calculator open)")
+
+	// 5-7) One EC2 service per planned item
+	for idx, it := range res.Items {
+		if idx < cp.Done {
+			continue
+		}
+		// Once the configurator is open there is no service finder on the
+		// page: a retry only configures the instance again.
+		attempt, opened := 0, false
+		err := runStep(ctx, policies, StepConfigure, func(ctx context.Context) error {
+			attempt++
+			var err error
+			if !opened {
+				if err = page.OpenEC2Configurator(ctx); err != nil {
+					err = fmt.Errorf("could not open 'Configure Amazon EC2' for planned item %d: %w", idx+1, err)
+				}
+				opened = err == nil
+			}
+			if err == nil {
+				err = page.SetInstance(ctx, it, o.Purchase, o.hoursPerMonth())
+			}
+			if err != nil {
+				page.Snapshot(fmt.Sprintf("(%s failed, attempt %d)", it.Name, attempt))
+			}
+			return err
+		})
+		if err != nil {
+			return Result{}, err
+		}
+		page.Snapshot(fmt.Sprintf("(configured %s)", it.Name))
+		if err := runStep(ctx, policies, StepSave, page.SaveAndAdd); err != nil {
+			page.Snapshot(fmt.Sprintf("(save/add %s failed)", it.Name))
 			return Result{}, fmt.Errorf("could not save/add EC2 %s: %w", it.Name, err)
 		}
-		dumpHTML(bctx, fmt.Sprintf("(after save/add %s)", it.Name))
+		page.Snapshot(fmt.Sprintf("(after save/add %s)", it.Name))
+		cp.Done = idx + 1
+		if err := cp.save(runsDir); err != nil {
+			l.Warn("could not save checkpoint", "err", err)
+		}
 	}
 
 	// After adding every planned service, only then view the summary
-	if err := clickViewSummary(bctx); err != nil {
-		dumpHTML(bctx, "(view summary failed)")
+	if err := runStep(ctx, policies, StepSummary, page.ViewSummary); err != nil {
+		page.Snapshot("(view summary failed)")
 		return Result{}, fmt.Errorf("could not click 'View summary': %w", err)
 	}
-	_ = waitVisibleWithTimeout(bctx, shareBtnXPath, byXPath, 5*time.Second)
+	page.Snapshot("(summary)")
+	if err := o.reconcile(ctx, page, policies, &res); err != nil {
+		return Result{}, err
+	}
 
 	// 8) Rename (optional)
 	name := strings.TrimSpace(o.EstimateName)
 	if name == "" {
 		name = "Estimate-" + time.Now().Format("20060102-150405")
 	}
-	log.Printf("[8/10] Renaming estimate to %q (if controls are present)...", name)
-	clickAny(bctx, []selector{{s: editNameLinkCSS, by: byCSS}, {s: editNameLinkXPath, by: byXPath}})
-	if exists(bctx, nameInputCSS, byCSS) {
-		_ = typeInto(bctx, nameInputCSS, byCSS, name)
-		clickAny(bctx, []selector{{s: saveNameBtnXPath, by: byXPath}})
+	if err := runStep(ctx, policies, StepRename, func(ctx context.Context) error {
+		return page.Rename(ctx, name)
+	}); err != nil {
+		l.Warn("rename skipped", "err", err)
 	}
 
-	// 9) Open Share and handle consent
-	log.Printf("[9/10] Opening 'Share' modal...")
-	_ = scrollToTop(bctx)
-	if err := clickRobust(bctx, shareBtnXPath, byXPath); err != nil {
-		return Result{}, fmt.Errorf("could not open Share dialog/button: %w", err)
+	// 9-10) Share and read the public link
+	var shareURL string
+	if err := runStep(ctx, policies, StepShare, func(ctx context.Context) error {
+		shareURL, err = page.Share(ctx)
+		return err
+	}); err != nil {
+		return Result{}, err
+	}
+	l.Info("estimate shared", "share_url", shareURL)
+	cp.ShareURL = shareURL
+	if err := cp.save(runsDir); err != nil {
+		l.Warn("could not save checkpoint", "err", err)
 	}
-	log.Printf("        Share clicked, handling consent if present...")
-	dismissCookieBanner(bctx)
 
-	shareURL := handleShareConsent(bctx)
+	res.ShareURL = shareURL
+	return res, nil
+}
 
-	// 10) Buscar link (backoff 1s → 3s → 5s; reabrir Share se necessário)
-	log.Printf("[10/10] Waiting for public link...")
-	if !looksLikeShareURL(shareURL) {
-		for _, d := range []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second} {
-			_ = chromedp.Run(bctx, chromedp.Sleep(d))
-			shareURL = getShareURLFromInputs(bctx)
-			if looksLikeShareURL(shareURL) {
-				break
-			}
+// reconcile reads the calculator's totals back from the summary into
+// res.Calculator and compares them with the plan.
+func (o *Orchestrator) reconcile(ctx context.Context, page CalculatorPage, policies map[string]RetryPolicy, res *Result) error {
+	l := logger(ctx)
+	var sum Summary
+	if err := runStep(ctx, policies, StepReadSummary, func(ctx context.Context) (err error) {
+		sum, err = page.ReadSummary(ctx)
+		return err
+	}); err != nil {
+		if o.StrictTotals {
+			return fmt.Errorf("could not read the calculator's totals: %w", err)
 		}
+		l.Warn("could not read the calculator's totals", "err", err)
+		return nil
 	}
-	if !looksLikeShareURL(shareURL) {
-		// reabrir Share e tentar de novo rapidamente
-		_ = clickWithTimeout(bctx, shareBtnXPath, byXPath, 3*time.Second)
-		_ = chromedp.Run(bctx, chromedp.Sleep(800*time.Millisecond))
-		shareURL = getShareURLFromInputs(bctx)
-	}
-	if !looksLikeShareURL(shareURL) {
-		shareURL = getShareURLAnywhere(bctx)
-	}
-	if !looksLikeShareURL(shareURL) {
-		shareURL = waitForShareLink(bctx, 3, 300*time.Millisecond)
+	rec := reconcile(res.Items, sum, tolOrDefault(o.Tolerance))
+	res.Calculator = &rec
+	l.Info("calculator totals", "monthly", rec.Monthly, "planned", rec.PlannedMonthly, "upfront", rec.Upfront, "relative_error", rec.RelativeError)
+	for _, issue := range rec.Issues {
+		l.Warn("calculator differs from the plan", "issue", issue)
 	}
-	dumpHTML(bctx, "(final snapshot)")
-
-	if strings.TrimSpace(shareURL) == "" {
-		return Result{}, fmt.Errorf("share link did not appear; see tmp.html")
+	if o.StrictTotals && !rec.OK() {
+		page.Snapshot("(totals differ)")
+		return fmt.Errorf("the calculator's estimate differs from the plan: %s", strings.Join(rec.Issues, "; "))
 	}
-	log.Printf("[DONE] Share URL: %s", shareURL)
-
-	relErr := 0.0
-	if o.TargetMRR > 0 {
-		relErr = math.Abs(totalApprox-o.TargetMRR) / o.TargetMRR
-	}
-
-	return Result{
-		ShareURL:      shareURL,
-		RegionLabel:   regionLabelFromCode(o.RegionCode),
-		AchievedMRR:   totalApprox,
-		RelativeError: relErr,
-	}, nil
+	return nil
 }
 
 // ---- View summary helper ----
 
-func clickViewSummary(ctx context.Context) error {
+func clickViewSummary(ctx context.Context, sp *SelectorPack) error {
 	_ = scrollToBottom(ctx)
-	if err := clickWithTimeout(ctx, viewSummaryBtnCSS, byCSS, 5*time.Second); err == nil {
-		_ = chromedp.Run(ctx, chromedp.Sleep(500*time.Millisecond))
-		return nil
-	}
-	fallback := `//*[@id='estimate-button'] | //*[normalize-space()='Save and view summary']/ancestor::*[self::button or self::a]`
-	if err := clickWithTimeout(ctx, fallback, byXPath, 5*time.Second); err != nil {
+	if err := clickElement(ctx, sp.el("view_summary"), 10*time.Second); err != nil {
 		return err
 	}
 	_ = chromedp.Run(ctx, chromedp.Sleep(500*time.Millisecond))
@@ -293,24 +468,31 @@ func clickViewSummary(ctx context.Context) error {
 // ---- Consent / Share helpers ----
 
 // Abre (ou confirma aberto) o modal de Share com timeout curto.
-func ensureShareModalOpen(ctx context.Context) bool {
+func ensureShareModalOpen(ctx context.Context, sp *SelectorPack) bool {
 	// já está aberto?
-	if exists(ctx, shareModalTitleXPath, byXPath) || exists(ctx, shareLinkInputXPath, byXPath) {
+	if shareModalVisible(ctx, sp) {
 		return true
 	}
 	// tenta abrir
 	_ = scrollToTop(ctx)
-	if err := clickWithTimeout(ctx, shareBtnXPath, byXPath, 3*time.Second); err != nil {
+	if err := clickElement(ctx, sp.el("share_button"), 3*time.Second); err != nil {
 		return false
 	}
-	_ = waitVisibleWithTimeout(ctx, shareConsentDialogXPath, byXPath, 3*time.Second)
+	_ = waitElement(ctx, sp.el("share_dialog"), 3*time.Second)
 	// título do modal ou campo de link visível?
-	return exists(ctx, shareModalTitleXPath, byXPath) || exists(ctx, shareLinkInputXPath, byXPath)
+	return shareModalVisible(ctx, sp)
+}
+
+func shareModalVisible(ctx context.Context, sp *SelectorPack) bool {
+	modal := sp.el("share_modal_title")
+	modal.sels = append(modal.sels, sp.el("share_link_input").sels...)
+	_, ok := find(ctx, modal)
+	return ok
 }
 
 // Tenta ler o link diretamente do(s) input(s) do modal (sem clicar em "Copy").
-func tryReadShareURLFromModal(ctx context.Context) string {
-	if v := getShareURLFromInputs(ctx); looksLikeShareURL(v) {
+func tryReadShareURLFromModal(ctx context.Context, sp *SelectorPack) string {
+	if v := getShareURLFromInputs(ctx, sp); looksLikeShareURL(v) {
 		return v
 	}
 	// às vezes o input está em um shadow root; tentar varredura ampla
@@ -321,26 +503,24 @@ func tryReadShareURLFromModal(ctx context.Context) string {
 }
 
 // Fluxo robusto: prioriza ler o link *antes* do clique; se fechar o modal, reabre e lê.
-func handleShareConsent(ctx context.Context) string {
+func handleShareConsent(ctx context.Context, sp *SelectorPack) string {
 	// 1) Aceita consentimento (se existir) — best effort
-	_ = clickWithTimeout(ctx, shareAgreeContinueBtnCSS, byCSS, 5*time.Second)
+	_ = clickElement(ctx, sp.el("share_agree"), 5*time.Second)
 	dumpHTML(ctx, "(post-agree)")
 	_ = chromedp.Run(ctx, chromedp.Sleep(800*time.Millisecond))
 
 	// 2) Garante modal aberto e tenta ler sem clicar
-	_ = ensureShareModalOpen(ctx)
-	if sharedUrl := tryReadShareURLFromModal(ctx); looksLikeShareURL(sharedUrl) {
+	_ = ensureShareModalOpen(ctx, sp)
+	if sharedUrl := tryReadShareURLFromModal(ctx, sp); looksLikeShareURL(sharedUrl) {
 		return sharedUrl
 	}
 
 	// 3) Até 3 tentativas: clicar em "Copy", reabrir modal (se fechar) e ler o input
 	for i := 1; i <= 3; i++ {
-		log.Printf("        [share] try %d/3: copy, reopen-if-needed, read input", i)
+		logger(ctx).Debug("copying public link", "try", i, "of", 3)
 
 		// clica no botão "Copy public link" se estiver visível; caso contrário tenta por texto
-		if exists(ctx, copyPublicLinkXPath, byXPath) {
-			_ = clickRobust(ctx, copyPublicLinkXPath, byXPath)
-		} else {
+		if err := clickElement(ctx, sp.el("copy_public_link"), time.Second); err != nil {
 			_ = deepClickButtonByText(ctx, "Copy public link")
 		}
 
@@ -348,19 +528,19 @@ func handleShareConsent(ctx context.Context) string {
 		_ = chromedp.Run(ctx, chromedp.Sleep(800*time.Millisecond))
 
 		// tenta ler de imediato (caso o modal continue aberto)
-		if sharedUrl := tryReadShareURLFromModal(ctx); looksLikeShareURL(sharedUrl) {
+		if sharedUrl := tryReadShareURLFromModal(ctx, sp); looksLikeShareURL(sharedUrl) {
 			return sharedUrl
 		}
 
 		// se o modal fechou, reabra e leia
-		if ensureShareModalOpen(ctx) {
-			if sharedUrl := tryReadShareURLFromModal(ctx); looksLikeShareURL(sharedUrl) {
+		if ensureShareModalOpen(ctx, sp) {
+			if sharedUrl := tryReadShareURLFromModal(ctx, sp); looksLikeShareURL(sharedUrl) {
 				return sharedUrl
 			}
 		}
 
 		// fallback: varredura no DOM e snapshot
-		if sharedUrl := getShareURLAnywhere(ctx); looksLikeShareURL(sharedUrl) {
+		if sharedUrl := getShareURLAnywhere(ctx, sp); looksLikeShareURL(sharedUrl) {
 			return sharedUrl
 		}
 		if snapURL := dumpAndExtract(ctx, fmt.Sprintf("(share try %d)", i)); looksLikeShareURL(snapURL) {
@@ -369,29 +549,16 @@ func handleShareConsent(ctx context.Context) string {
 	}
 
 	// Última cartada: reabrir o modal e tentar novamente a leitura direta
-	if ensureShareModalOpen(ctx) {
-		if sharedUrl := tryReadShareURLFromModal(ctx); looksLikeShareURL(sharedUrl) {
+	if ensureShareModalOpen(ctx, sp) {
+		if sharedUrl := tryReadShareURLFromModal(ctx, sp); looksLikeShareURL(sharedUrl) {
 			return sharedUrl
 		}
 	}
 	return ""
 }
 
-func clickWithTimeout(ctx context.Context, sel string, by queryBy, d time.Duration) error {
-	c2, cancel := context.WithTimeout(ctx, d)
-	defer cancel()
-	return clickRobust(c2, sel, by)
-}
-
-func waitVisibleWithTimeout(ctx context.Context, sel string, by queryBy, d time.Duration) error {
-	c2, cancel := context.WithTimeout(ctx, d)
-	defer cancel()
-	opts := queryOpts(by)
-	return chromedp.Run(c2, chromedp.WaitVisible(sel, opts...))
-}
-
-func getShareURLAnywhere(ctx context.Context) string {
-	if v := getShareURLFromInputs(ctx); looksLikeShareURL(v) {
+func getShareURLAnywhere(ctx context.Context, sp *SelectorPack) string {
+	if v := getShareURLFromInputs(ctx, sp); looksLikeShareURL(v) {
 		return v
 	}
 	if v := scanAnyShareInput(ctx); looksLikeShareURL(v) {
@@ -403,36 +570,24 @@ func getShareURLAnywhere(ctx context.Context) string {
 	return ""
 }
 
-func getShareURLFromInputs(ctx context.Context) string {
-	if v, ok := readInputValue(ctx, `input[aria-label="Copy public link"]`, byCSS); ok {
-		v = html.UnescapeString(strings.TrimSpace(v))
-		if looksLikeShareURL(v) {
-			return v
-		}
-	}
-	if v, ok := readInputValue(ctx, shareLinkInputCSS, byCSS); ok {
-		v = html.UnescapeString(strings.TrimSpace(v))
-		if looksLikeShareURL(v) {
-			return v
-		}
-	}
-	if v, ok := readInputValue(ctx, shareLinkInputXPath, byXPath); ok {
-		v = html.UnescapeString(strings.TrimSpace(v))
-		if looksLikeShareURL(v) {
-			return v
+func getShareURLFromInputs(ctx context.Context, sp *SelectorPack) string {
+	for _, sel := range sp.el("share_link_input").sels {
+		if v, ok := readInputValue(ctx, sel.s, sel.by); ok {
+			v = html.UnescapeString(strings.TrimSpace(v))
+			if looksLikeShareURL(v) {
+				return v
+			}
 		}
 	}
 	return ""
 }
 
-func waitForShareLink(ctx context.Context, tries int, delay time.Duration) string {
+func waitForShareLink(ctx context.Context, sp *SelectorPack, tries int, delay time.Duration) string {
 	for i := 0; i < tries; i++ {
-		if v := getShareURLFromInputs(ctx); looksLikeShareURL(v) {
+		if v := getShareURLFromInputs(ctx, sp); looksLikeShareURL(v) {
 			return v
 		}
-		if exists(ctx, copyPublicLinkXPath, byXPath) {
-			_ = clickRobust(ctx, copyPublicLinkXPath, byXPath)
-		} else {
+		if err := clickElement(ctx, sp.el("copy_public_link"), time.Second); err != nil {
 			_ = deepClickButtonByText(ctx, "Copy public link")
 		}
 		if delay > 0 {
@@ -478,19 +633,33 @@ func deepScanShareInput(ctx context.Context) string {
 }
 
 func deepClickButtonByText(ctx context.Context, label string) bool {
+	return deepClickByText(ctx, label, `button,[role="button"]`, false)
+}
+
+// deepClickOptionByExactText clicks the button or option whose whole text is
+// label, for labels that start other labels ("Windows Server" and "Windows
+// Server with SQL Server Standard").
+func deepClickOptionByExactText(ctx context.Context, label string) bool {
+	return deepClickByText(ctx, label, `button,[role="button"],[role="option"],li`, true)
+}
+
+// deepClickByText clicks the first element matching query, in the document
+// or any shadow root, whose text is label or, unless exact, contains it.
+func deepClickByText(ctx context.Context, label, query string, exact bool) bool {
 	js := fmt.Sprintf(`(function(){
 		const target = %q.toLowerCase();
-		function text(el){ return (el.innerText||el.textContent||'').trim().toLowerCase(); }
+		const exact = %t;
+		function text(el){ return (el.innerText||el.textContent||'').replace(/\s+/g,' ').trim().toLowerCase(); }
 		function clickIfMatch(el){
 			const t = text(el);
-			if(t===target || t.includes(target)) { el.click(); return true; }
+			if(t===target || (!exact && t.includes(target))) { el.click(); return true; }
 			return false;
 		}
 		function dfs(root){
 			if(!root) return false;
 			let list = [];
 			if(root.querySelectorAll){
-				list = Array.from(root.querySelectorAll('button,[role="button"]'));
+				list = Array.from(root.querySelectorAll(%q));
 			}
 			for(const el of list){
 				if(clickIfMatch(el)) return true;
@@ -503,11 +672,11 @@ func deepClickButtonByText(ctx context.Context, label string) bool {
 			return false;
 		}
 		return dfs(document);
-	})()`, label)
+	})()`, label, exact, query)
 	var ok bool
 	_ = chromedp.Run(ctx, chromedp.Evaluate(js, &ok))
 	if ok {
-		log.Printf("   clicked(deep): %s", label)
+		logger(ctx).Debug("clicked", "method", "deep", "label", label)
 	}
 	return ok
 }
@@ -544,12 +713,12 @@ func clickRobust(ctx context.Context, sel string, by queryBy) error {
 		chromedp.WaitVisible(sel, opts...),
 		chromedp.Click(sel, opts...),
 	); err == nil {
-		log.Printf("   clicked: %s", shortSel(sel))
+		logger(ctx).Debug("clicked", "selector", sel)
 		return nil
 	}
 
 	if err := chromedp.Run(ctx, chromedp.MouseClickNode(nodes[0])); err == nil {
-		log.Printf("   clicked(mouse): %s", shortSel(sel))
+		logger(ctx).Debug("clicked", "method", "mouse", "selector", sel)
 		return nil
 	}
 
@@ -562,7 +731,7 @@ func clickRobust(ctx context.Context, sel string, by queryBy) error {
 	}
 	var res string
 	if err := chromedp.Run(ctx, chromedp.Evaluate(js, &res)); err == nil && res == "ok" {
-		log.Printf("   clicked(js): %s", shortSel(sel))
+		logger(ctx).Debug("clicked", "method", "js", "selector", sel)
 		return nil
 	}
 
@@ -587,13 +756,11 @@ func currentURL(ctx context.Context) string {
 	return loc
 }
 
-func clickAny(ctx context.Context, sels []selector) bool {
-	for _, sel := range sels {
-		if clickIfExists(ctx, sel.s, sel.by) {
-			return true
-		}
-	}
-	return false
+// clickAny waits for the element and clicks the first selector of its chain
+// that matches.
+func clickAny(ctx context.Context, e element) bool {
+	sel, ok := find(ctx, e)
+	return ok && clickIfExists(ctx, sel.s, sel.by)
 }
 
 func clickIfExists(ctx context.Context, sel string, by queryBy) bool {
@@ -604,21 +771,12 @@ func clickIfExists(ctx context.Context, sel string, by queryBy) bool {
 	}
 	_ = chromedp.Run(ctx, chromedp.ScrollIntoView(sel, opts...))
 	if err := chromedp.Run(ctx, chromedp.Click(sel, opts...)); err == nil {
-		log.Printf("   clicked: %s", shortSel(sel))
+		logger(ctx).Debug("clicked", "selector", sel)
 		return true
 	}
 	return false
 }
 
-func exists(ctx context.Context, sel string, by queryBy) bool {
-	var nodes []*cdp.Node
-	opts := queryOpts(by)
-	if err := chromedp.Run(ctx, chromedp.Nodes(sel, &nodes, opts...)); err != nil {
-		return false
-	}
-	return len(nodes) > 0
-}
-
 func typeInto(ctx context.Context, sel string, by queryBy, text string) error {
 	opts := queryOpts(by)
 	_ = chromedp.Run(ctx,
@@ -734,23 +892,30 @@ func fullHTML(ctx context.Context) (string, error) {
 	return htmlStr, nil
 }
 
-func dumpHTML(ctx context.Context, note string) {
-	if htmlStr, err := fullHTML(ctx); err == nil {
-		_ = os.WriteFile("tmp.html", []byte(htmlStr), 0644)
-		if note != "" {
-			log.Printf("        Wrote HTML snapshot to tmp.html %s", note)
-		} else {
-			log.Printf("        Wrote HTML snapshot to tmp.html")
-		}
+// saveSnapshot captures the page's HTML and writes it as the run's next
+// numbered snapshot (see runArtifacts), or to tmp.html outside a run.
+func saveSnapshot(ctx context.Context, note string) (string, error) {
+	htmlStr, err := fullHTML(ctx)
+	if err != nil {
+		logger(ctx).Warn("could not capture HTML snapshot", "note", note, "err", err)
+		return "", err
+	}
+	path := "tmp.html"
+	if a := artifactsFrom(ctx); a != nil {
+		path, err = a.snapshot(ctx, note, htmlStr)
 	} else {
-		if note != "" {
-			log.Printf("        Could not capture HTML snapshot %s: %v", note, err)
-		} else {
-			log.Printf("        Could not capture HTML snapshot: %v", err)
-		}
+		err = os.WriteFile(path, []byte(htmlStr), 0644)
 	}
+	if err != nil {
+		logger(ctx).Warn("could not write HTML snapshot", "note", note, "err", err)
+	} else {
+		logger(ctx).Debug("wrote HTML snapshot", "path", path, "note", note)
+	}
+	return htmlStr, nil
 }
 
+func dumpHTML(ctx context.Context, note string) { _, _ = saveSnapshot(ctx, note) }
+
 func dismissCookieBanner(ctx context.Context) {
 	js := `(function(){
 		var root = document.querySelector('#awsccc-cb-c');
@@ -763,7 +928,7 @@ func dismissCookieBanner(ctx context.Context) {
 	var res string
 	_ = chromedp.Run(ctx, chromedp.Evaluate(js, &res))
 	if res != "" {
-		log.Printf("        Cookie banner: %s", res)
+		logger(ctx).Debug("cookie banner", "result", res)
 	}
 }
 
@@ -784,32 +949,13 @@ func queryOpts(by queryBy) []chromedp.QueryOption {
 	}
 }
 
-func shortSel(s string) string {
-	s = strings.ReplaceAll(s, "\n", " ")
-	if len(s) > 96 {
-		return s[:96] + "…"
-	}
-	return s
-}
-
 func dumpAndExtract(ctx context.Context, note string) string {
-	htmlStr, err := fullHTML(ctx)
-	if err == nil {
-		_ = os.WriteFile("tmp.html", []byte(htmlStr), 0644)
-		if note != "" {
-			log.Printf("        Wrote HTML snapshot to tmp.html %s", note)
-		} else {
-			log.Printf("        Wrote HTML snapshot to tmp.html")
-		}
-		if v := extractShareURLFromHTML(htmlStr); looksLikeShareURL(v) {
-			return html.UnescapeString(strings.TrimSpace(v))
-		}
-	} else {
-		if note != "" {
-			log.Printf("        Could not capture HTML snapshot %s: %v", note, err)
-		} else {
-			log.Printf("        Could not capture HTML snapshot: %v", err)
-		}
+	htmlStr, err := saveSnapshot(ctx, note)
+	if err != nil {
+		return ""
+	}
+	if v := extractShareURLFromHTML(htmlStr); looksLikeShareURL(v) {
+		return html.UnescapeString(strings.TrimSpace(v))
 	}
 	return ""
 }
@@ -820,216 +966,57 @@ func extractShareURLFromHTML(s string) string {
 	return m
 }
 
-func ensureOnDemand(ctx context.Context, d time.Duration) error {
-	if err := clickWithTimeout(ctx, onDemandOptionXPath, byXPath, d); err != nil {
-		_ = deepClickButtonByText(ctx, "On-Demand")
-	}
-	return nil
-}
-
 // (REPOSTO) Garante que filtros "Any Memory" e "Any vCPUs" sejam ativados
-func ensureAnyFilters(ctx context.Context, d time.Duration) error {
-	_ = clickWithTimeout(ctx, anyMemoryTriggerXPath, byXPath, d)
+func ensureAnyFilters(ctx context.Context, sp *SelectorPack, d time.Duration) error {
+	_ = clickElement(ctx, sp.el("any_memory"), d)
 	_ = chromedp.Run(ctx, chromedp.Sleep(100*time.Millisecond))
-	_ = clickWithTimeout(ctx, anyVcpuTriggerXPath, byXPath, d)
+	_ = clickElement(ctx, sp.el("any_vcpus"), d)
 	_ = chromedp.Run(ctx, chromedp.Sleep(100*time.Millisecond))
 	return nil
 }
 
 // ---- Save/Add helper ----
 
-func clickSaveAndAddService(ctx context.Context) error {
-	for i := 1; i <= 3; i++ {
-		log.Printf("        [save/add] attempt %d/3", i)
+// clickSaveAndAddService clicks "Save and add service" until the service
+// finder is back, as often as policy allows.
+func clickSaveAndAddService(ctx context.Context, sp *SelectorPack, policy RetryPolicy) error {
+	i := 0
+	return policy.do(ctx, StepSaveClick, nil, func(ctx context.Context) error {
+		i++
+		logger(ctx).Debug("clicking save and add", "attempt", i, "of", policy.Attempts)
 		_ = scrollToBottom(ctx)
-		_ = chromedp.Run(ctx, chromedp.WaitVisible(appFooterCSS, chromedp.ByQuery))
-		if err := clickWithTimeout(ctx, saveAndAddBtnFooterCSS, byCSS, 5*time.Second); err != nil {
-			_ = clickWithTimeout(ctx, saveAndAddXPath, byXPath, 5*time.Second)
-		}
+		_ = waitElement(ctx, sp.el("app_footer"), 0)
+		_ = clickElement(ctx, sp.el("save_and_add"), 10*time.Second)
 		_ = chromedp.Run(ctx, chromedp.Sleep(500*time.Millisecond))
-		if err := waitVisibleWithTimeout(ctx, findServiceInputCSS, byCSS, 5*time.Second); err == nil {
-			log.Printf("        [save/add] service added (Find Service visible)")
-			return nil
+		if err := waitElement(ctx, sp.el("find_service"), 5*time.Second); err != nil {
+			dumpHTML(ctx, fmt.Sprintf("(after save/add attempt %d)", i))
+			return fmt.Errorf("Save and add service did not complete")
 		}
-		dumpHTML(ctx, fmt.Sprintf("(after save/add attempt %d)", i))
-	}
-	return fmt.Errorf("Save and add service did not complete")
-}
-
-// ---- Planning (greedy desc, ignorando *.nano) ----
-
-type ec2Option struct {
-	Name    string
-	Hourly  float64
-	Monthly float64
-}
-
-type planItem struct {
-	Name    string
-	Hourly  float64
-	Monthly float64
-	Count   int
-}
-
-// YAML model
-type pricingDoc struct {
-	EC2 []struct {
-		Name      string  `yaml:"name"`
-		Hourly    float64 `yaml:"hourly,omitempty"`
-		Monthly   float64 `yaml:"monthly,omitempty"`
-		VCpus     int     `yaml:"vcpus,omitempty"`
-		MemoryGiB string  `yaml:"memory_gib,omitempty"`
-	} `yaml:"ec2"`
-}
-
-// Lê EC2_PRICING_YAML ou ./pricing.yaml. Filtra *.nano
-func ec2Catalog() []ec2Option {
-	path := os.Getenv("EC2_PRICING_YAML")
-	if strings.TrimSpace(path) == "" {
-		path = "pricing.yaml"
-	}
-	b, err := os.ReadFile(path)
-	if err != nil {
-		log.Printf("        could not read pricing file %q: %v", path, err)
+		logger(ctx).Info("service added")
 		return nil
-	}
-	opts := parsePricingYAML(b)
-	if len(opts) == 0 {
-		log.Printf("        WARNING: no EC2 entries loaded from %s", path)
-	} else {
-		log.Printf("        loaded %d pricing entries from %s (nano filtered out)", len(opts), path)
-	}
-	return opts
-}
-
-func parsePricingYAML(b []byte) []ec2Option {
-	const HPM = 730.0
-	var doc pricingDoc
-	if err := yaml.Unmarshal(b, &doc); err != nil {
-		log.Printf("        YAML unmarshal failed: %v", err)
-		return nil
-	}
-	m := map[string]ec2Option{}
-	for _, it := range doc.EC2 {
-		name := strings.TrimSpace(it.Name)
-		if name == "" {
-			continue
-		}
-		if strings.Contains(name, ".nano") {
-			continue
-		}
-		hr := it.Hourly
-		mo := it.Monthly
-		if mo <= 0 && hr > 0 {
-			mo = hr * HPM
-		}
-		if hr <= 0 && mo > 0 {
-			hr = mo / HPM
-		}
-		if mo <= 0 {
-			continue
-		}
-		if old, ok := m[name]; !ok || mo < old.Monthly {
-			m[name] = ec2Option{Name: name, Hourly: hr, Monthly: mo}
-		}
-	}
-	opts := make([]ec2Option, 0, len(m))
-	for _, v := range m {
-		opts = append(opts, v)
-	}
-	sort.Slice(opts, func(i, j int) bool { return opts[i].Monthly < opts[j].Monthly })
-	return opts
-}
-
-// Heurística solicitada (descendente, while current+price < target)
-func planGreedyEC2(targetMRR float64, _ float64) []planItem {
-	opts := ec2Catalog()
-	if len(opts) == 0 || targetMRR <= 0 {
-		return nil
-	}
-
-	cp := append([]ec2Option(nil), opts...)
-	sort.Slice(cp, func(i, j int) bool { return cp[i].Monthly > cp[j].Monthly })
-
-	var plan []planItem
-	current := 0.0
-	for _, o := range cp {
-		if o.Monthly <= 0 {
-			continue
-		}
-		count := 0
-		for (current + o.Monthly) < targetMRR {
-			current += o.Monthly
-			count++
-		}
-		if count > 0 {
-			plan = append(plan, planItem{
-				Name:    o.Name,
-				Hourly:  o.Hourly,
-				Monthly: o.Monthly,
-				Count:   count,
-			})
-		}
-		if current >= targetMRR {
-			break
-		}
-	}
-	return compactPlan(plan)
-}
-
-func compactPlan(in []planItem) []planItem {
-	if len(in) == 0 {
-		return in
-	}
-	m := map[string]*planItem{}
-	for _, it := range in {
-		if it.Count <= 0 {
-			continue
-		}
-		if ex, ok := m[it.Name]; ok {
-			ex.Count += it.Count
-		} else {
-			cp := it
-			m[it.Name] = &cp
-		}
-	}
-	out := make([]planItem, 0, len(m))
-	for _, v := range m {
-		out = append(out, *v)
-	}
-	sort.Slice(out, func(i, j int) bool { return out[i].Monthly > out[j].Monthly })
-	return out
+	})
 }
 
 // ---- UI helpers ----
 
-func selectInstanceByName(ctx context.Context, instance string, timeout time.Duration) error {
-	if exists(ctx, instanceSearchInputCSS, byCSS) {
-		_ = typeInto(ctx, instanceSearchInputCSS, byCSS, instance)
+func selectInstanceByName(ctx context.Context, sp *SelectorPack, instance string, timeout time.Duration) error {
+	if search, ok := find(ctx, sp.el("instance_search")); ok {
+		_ = typeInto(ctx, search.s, search.by, instance)
 		_ = chromedp.Run(ctx, chromedp.Sleep(200*time.Millisecond))
 	}
-	x := fmt.Sprintf(`//label[.//text()[contains(., '%s')]] | //span[contains(normalize-space(),'%s')]/ancestor::label | //tr[.//*[contains(normalize-space(),'%s')]]//label`, instance, instance, instance)
-	return clickWithTimeout(ctx, x, byXPath, timeout)
+	return clickElement(ctx, sp.el("instance_option", instance), timeout)
 }
-func setInstanceCount(ctx context.Context, count int) error {
+func setInstanceCount(ctx context.Context, sp *SelectorPack, count int) error {
 	if count < 1 {
 		count = 1
 	}
 	val := fmt.Sprintf("%d", count)
 
-	// 1) Mesmo alvo do seu Puppeteer
-	if err := setInputValueJS(ctx, numberInstancesInputCSSExact, byCSS, val); err == nil {
-		if v, ok := readInputValue(ctx, numberInstancesInputCSSExact, byCSS); ok && strings.TrimSpace(v) == val {
-			log.Printf("        [count] set via Puppeteer-exact aria-label → %s", val)
-			return nil
-		}
-	}
-
-	// 2) Fallback: nosso XPath já existente para "Number of instances"
-	if exists(ctx, numberInstancesInputXPath, byXPath) {
-		if err := setInputValueJS(ctx, numberInstancesInputXPath, byXPath, val); err == nil {
-			if v, ok := readInputValue(ctx, numberInstancesInputXPath, byXPath); ok && strings.TrimSpace(v) == val {
-				log.Printf("        [count] set via XPath(aria-label contains 'Number of instances') → %s", val)
+	// Cada seletor da cadeia "instance_count", na ordem do pack
+	for _, sel := range sp.el("instance_count").sels {
+		if err := setInputValueJS(ctx, sel.s, sel.by, val); err == nil {
+			if v, ok := readInputValue(ctx, sel.s, sel.by); ok && strings.TrimSpace(v) == val {
+				logger(ctx).Debug("instance count set", "selector", sel.s, "count", val)
 				return nil
 			}
 		}
@@ -0,0 +1,267 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ---- Pricing catalog lint ----
+
+// Lint severities.
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// monthlyMismatch is how far (relative) monthly may be from hourly × the
+// file's hours_per_month (730 when unset) before an entry declaring both is
+// reported.
+const monthlyMismatch = 0.01
+
+// LintIssue is one problem found in a pricing file. Line is 0 when the
+// problem is not tied to a single line.
+type LintIssue struct {
+	Severity string
+	Line     int
+	Region   string
+	Name     string
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	var where []string
+	if i.Line > 0 {
+		where = append(where, fmt.Sprintf("line %d", i.Line))
+	}
+	if i.Region != "" {
+		where = append(where, i.Region)
+	}
+	if i.Name != "" {
+		where = append(where, i.Name)
+	}
+	if len(where) == 0 {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, strings.Join(where, " "), i.Message)
+}
+
+// LintErrors counts the issues with error severity.
+func LintErrors(issues []LintIssue) int {
+	n := 0
+	for _, i := range issues {
+		if i.Severity == LintError {
+			n++
+		}
+	}
+	return n
+}
+
+// pricingFields are the keys understood in each section of a pricing file.
+var pricingFields = map[string][]string{
+	"":      {"version", "filters", "discounts", "os_surcharges", "hours_per_month", "region", "ec2", "regions"},
+	"entry": {"name", "hourly", "monthly", "vcpus", "memory_gib", "purchase", "upfront", "os"},
+}
+
+// entryAt is a pricing entry with the line it was declared on.
+type entryAt struct {
+	pricingEntry
+	line int
+	keys []*yaml.Node
+}
+
+// LintPricing checks a pricing file the way parsePricingYAML reads it and
+// reports everything it would drop or misread: YAML errors, unknown keys,
+// duplicate entries, hourly/monthly disagreements, zero or negative prices,
+// malformed instance names, unknown purchase options or operating systems,
+// and missing vcpus/memory_gib metadata (warnings: such entries are skipped
+// by workload sizing and OS surcharges).
+func LintPricing(b []byte) []LintIssue {
+	var issues []LintIssue
+	add := func(sev string, line int, region, name, format string, args ...any) {
+		issues = append(issues, LintIssue{Severity: sev, Line: line, Region: region, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		add(LintError, 0, "", "", "invalid YAML: %v", err)
+		return issues
+	}
+	doc, err := decodePricingDoc(b)
+	if err != nil {
+		add(LintError, 0, "", "", "%v", err)
+		return issues
+	}
+	if len(root.Content) == 0 {
+		add(LintError, 0, "", "", "empty pricing file")
+		return issues
+	}
+	top := root.Content[0]
+	unknownKeys(top, pricingFields[""], func(k *yaml.Node) {
+		add(LintWarning, k.Line, "", "", "unknown key %q is ignored", k.Value)
+	})
+
+	if err := doc.Filters.Validate(); err != nil {
+		add(LintError, mappingLine(top, "filters"), "", "", "filters: %v", err)
+	}
+	for k, d := range doc.Discounts {
+		if _, err := ParsePurchaseOption(k); err != nil {
+			add(LintError, mappingLine(top, "discounts"), "", "", "discounts: %v", err)
+		} else if d <= 0 || d >= 1 {
+			add(LintError, mappingLine(top, "discounts"), "", "", "discounts: %s must be between 0 and 1, got %v", k, d)
+		}
+	}
+	for k, fee := range doc.OSSurcharges {
+		if _, err := ParseOperatingSystem(k); err != nil {
+			add(LintError, mappingLine(top, "os_surcharges"), "", "", "os_surcharges: %v", err)
+		} else if fee <= 0 {
+			add(LintError, mappingLine(top, "os_surcharges"), "", "", "os_surcharges: %s must be positive, got %v", k, fee)
+		}
+	}
+
+	// Monthly prices are checked against the file's month, as the planner
+	// reads them; an invalid or unset one falls back to 730 hours.
+	hours := hoursPerMonth
+	if h := doc.HoursPerMonth; h < 0 || h > 744 || (h == 0 && mappingValue(top, "hours_per_month") != nil) {
+		add(LintError, mappingLine(top, "hours_per_month"), "", "", "hours_per_month must be between 1 and 744, got %v", h)
+	} else if h > 0 {
+		hours = h
+	}
+
+	lists := entryNodes(top, doc)
+	if len(lists) == 0 {
+		add(LintError, 0, "", "", "no EC2 entries (expected regions.<code>.ec2 or ec2)")
+		return issues
+	}
+
+	// Metadata is inherited by name across regions, as in parsePricingYAML.
+	hasVCPUs, hasMem := map[string]bool{}, map[string]bool{}
+	for _, list := range lists {
+		for _, e := range list {
+			hasVCPUs[e.Name] = hasVCPUs[e.Name] || e.VCpus > 0
+			hasMem[e.Name] = hasMem[e.Name] || e.MemoryGiB > 0
+		}
+	}
+
+	regions := make([]string, 0, len(lists))
+	for r := range lists {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		seen := map[string]int{}
+		for _, e := range lists[region] {
+			name := strings.TrimSpace(e.Name)
+			for _, k := range e.keys {
+				add(LintWarning, k.Line, region, name, "unknown key %q is ignored", k.Value)
+			}
+			if name == "" {
+				add(LintError, e.line, region, "", "entry without name")
+				continue
+			}
+			if _, ok := parseInstanceName(name); !ok {
+				add(LintError, e.line, region, name, "malformed instance type name")
+			}
+			p, err := ParsePurchaseOption(e.Purchase)
+			if err != nil {
+				add(LintError, e.line, region, name, "%v", err)
+			}
+			os, err := ParseOperatingSystem(e.OS)
+			if err != nil {
+				add(LintError, e.line, region, name, "%v", err)
+			}
+			key := priceKey(os, p)
+			if first, ok := seen[key+" "+name]; ok {
+				add(LintError, e.line, region, name, "duplicate entry for %s %s (first declared on line %d)", OSLabel(os), p.Label(), first)
+			} else {
+				seen[key+" "+name] = e.line
+			}
+
+			switch {
+			case e.Hourly < 0 || e.Monthly < 0 || e.Upfront < 0:
+				add(LintError, e.line, region, name, "negative price (hourly %v, monthly %v, upfront %v)", e.Hourly, e.Monthly, e.Upfront)
+			case e.Hourly == 0 && e.Monthly == 0 && e.Upfront == 0:
+				add(LintError, e.line, region, name, "no price (hourly, monthly and upfront are all zero)")
+			case e.Hourly > 0 && e.Monthly > 0:
+				if want := e.Hourly * hours; math.Abs(e.Monthly-want)/want > monthlyMismatch {
+					add(LintError, e.line, region, name, "monthly %.2f disagrees with hourly %v × %.0fh = %.2f", e.Monthly, e.Hourly, hours, want)
+				}
+			}
+			if e.Upfront > 0 && !p.IsCommitment() {
+				add(LintError, e.line, region, name, "upfront is only valid for RI/Savings Plans prices, not %s", p.Label())
+			}
+
+			if !hasVCPUs[name] {
+				add(LintWarning, e.line, region, name, "missing vcpus")
+			}
+			if !hasMem[name] {
+				add(LintWarning, e.line, region, name, "missing memory_gib")
+			}
+		}
+	}
+	return issues
+}
+
+// entryNodes returns the entries per region with their source lines. The
+// node walk mirrors pricingDoc.regionLists.
+func entryNodes(top *yaml.Node, doc pricingDoc) map[string][]entryAt {
+	lists := map[string][]entryAt{}
+	collect := func(region string, seq *yaml.Node) {
+		if seq == nil || seq.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, n := range seq.Content {
+			var e pricingEntry
+			_ = n.Decode(&e)
+			at := entryAt{pricingEntry: e, line: n.Line}
+			unknownKeys(n, pricingFields["entry"], func(k *yaml.Node) { at.keys = append(at.keys, k) })
+			lists[region] = append(lists[region], at)
+		}
+	}
+	if flat := mappingValue(top, "ec2"); flat != nil {
+		region := strings.TrimSpace(doc.Region)
+		if region == "" {
+			region = defaultRegion
+		}
+		collect(region, flat)
+	}
+	if regions := mappingValue(top, "regions"); regions != nil && regions.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(regions.Content); i += 2 {
+			collect(strings.TrimSpace(regions.Content[i].Value), mappingValue(regions.Content[i+1], "ec2"))
+		}
+	}
+	return lists
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func mappingLine(m *yaml.Node, key string) int {
+	if v := mappingValue(m, key); v != nil {
+		return v.Line
+	}
+	return 0
+}
+
+func unknownKeys(m *yaml.Node, known []string, fn func(*yaml.Node)) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if !containsString(known, m.Content[i].Value) {
+			fn(m.Content[i])
+		}
+	}
+}
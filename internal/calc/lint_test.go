@@ -0,0 +1,120 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLintPricing(t *testing.T) {
+	doc := `
+discounts:
+  ri-1yr-no-upfront: 1.5
+regions:
+  us-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.0816
+        vcpus: 2
+        memory_gib: 8
+      - name: m7g.large
+        hourly: 0.09
+      - name: c7g.large
+        hourly: 0.0725
+        monthly: 60
+        vcpus: 2
+        memory_gib: 4
+      - name: r7g.large
+        hourly: 0
+        vcpus: 2
+        memory_gib: 16
+      - name: bogus
+        hourly: 0.1
+        vcpus: 1
+        memory_gib: 1
+      - name: t4g.small
+        hourly: 0.0168
+        hourlyy: 0.0168
+  sa-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.1297
+`
+	issues := LintPricing([]byte(doc))
+	var errs, warns []string
+	for _, i := range issues {
+		if i.Severity == LintError {
+			errs = append(errs, i.String())
+		} else {
+			warns = append(warns, i.String())
+		}
+	}
+	wantErrs := []string{
+		"discounts: ri-1yr-no-upfront must be between 0 and 1",
+		"line 11 us-east-1 m7g.large: duplicate entry for Linux On-Demand (first declared on line 7)",
+		"us-east-1 c7g.large: monthly 60.00 disagrees with hourly",
+		"us-east-1 r7g.large: no price",
+		"us-east-1 bogus: malformed instance type name",
+	}
+	for _, w := range wantErrs {
+		if !containsSubstring(errs, w) {
+			t.Errorf("missing error %q in %v", w, errs)
+		}
+	}
+	if len(errs) != len(wantErrs) {
+		t.Errorf("got %d errors, want %d: %v", len(errs), len(wantErrs), errs)
+	}
+	for _, w := range []string{`t4g.small: unknown key "hourlyy"`, "t4g.small: missing vcpus", "t4g.small: missing memory_gib"} {
+		if !containsSubstring(warns, w) {
+			t.Errorf("missing warning %q in %v", w, warns)
+		}
+	}
+	// sa-east-1 inherits m7g.large metadata from us-east-1.
+	if containsSubstring(warns, "sa-east-1 m7g.large") {
+		t.Errorf("unexpected metadata warning: %v", warns)
+	}
+	if LintErrors(issues) != len(errs) {
+		t.Errorf("LintErrors = %d, want %d", LintErrors(issues), len(errs))
+	}
+}
+
+func TestLintPricingInvalidYAML(t *testing.T) {
+	issues := LintPricing([]byte("regions:\n  us-east-1:\n    ec2:\n      - name: m7g.large\n        hourly: abc\n"))
+	if LintErrors(issues) != 1 || !strings.Contains(issues[0].Message, "line 5") {
+		t.Fatalf("expected a YAML error on line 5, got %v", issues)
+	}
+	if issues := LintPricing([]byte("")); LintErrors(issues) != 1 {
+		t.Fatalf("expected an error for an empty file, got %v", issues)
+	}
+}
+
+func TestLintPricingHoursPerMonth(t *testing.T) {
+	doc := "hours_per_month: %s\nec2:\n  - {name: m7g.large, hourly: 0.08, vcpus: 2, memory_gib: 8}\n"
+	if issues := LintPricing([]byte(fmt.Sprintf(doc, "720"))); len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+	for _, bad := range []string{"0", "800"} {
+		if issues := LintPricing([]byte(fmt.Sprintf(doc, bad))); LintErrors(issues) != 1 || issues[0].Line != 1 {
+			t.Errorf("hours_per_month %s: expected an error on line 1, got %v", bad, issues)
+		}
+	}
+
+	// Monthly prices are checked against the file's month, not 730 hours.
+	priced := "hours_per_month: 720\nec2:\n  - {name: m7g.large, hourly: 0.08, monthly: %s, vcpus: 2, memory_gib: 8}\n"
+	if issues := LintPricing([]byte(fmt.Sprintf(priced, "57.60"))); len(issues) != 0 {
+		t.Fatalf("monthly of a 720h month: unexpected issues: %v", issues)
+	}
+	issues := LintPricing([]byte(fmt.Sprintf(priced, "58.40")))
+	if LintErrors(issues) != 1 || !strings.Contains(issues[0].Message, "× 720h = 57.60") {
+		t.Fatalf("monthly of a 730h month: expected a mismatch against 720h, got %v", issues)
+	}
+}
+
+func containsSubstring(list []string, sub string) bool {
+	for _, s := range list {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package calc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRunLogsRunAndSteps(t *testing.T) {
+	chdirTemp(t)
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, LogFormatJSON, slog.LevelDebug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &fakePage{fail: map[string]int{"SetInstance": 1}}
+	o := fakeOrchestrator(page)
+	o.RunID = "20261017-120000-acme"
+	o.Logger = l
+	if _, err := o.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := map[string]bool{}
+	retried := false
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("not a JSON line %q: %v", line, err)
+		}
+		if e["run"] != o.RunID {
+			t.Errorf("entry without the run ID: %s", line)
+		}
+		switch e["msg"] {
+		case "step done":
+			if _, ok := e["duration"].(float64); !ok {
+				t.Errorf("step entry without duration: %s", line)
+			}
+			steps[e["step"].(string)] = true
+		case "retrying":
+			retried = e["step"] == StepConfigure
+		}
+	}
+	for _, step := range []string{StepOpen, StepConfigure, StepSave, StepSummary, StepRename, StepShare} {
+		if !steps[step] {
+			t.Errorf("no log entry for step %s:\n%s", step, buf.String())
+		}
+	}
+	if !retried {
+		t.Errorf("the configure retry was not logged:\n%s", buf.String())
+	}
+}
+
+func TestNewLoggerRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", slog.LevelInfo); err == nil {
+		t.Fatal("expected an error for format xml")
+	}
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "", slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Info("shown", "step", StepOpen)
+	if got := buf.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "msg=shown step=open") {
+		t.Fatalf("unexpected text log %q", got)
+	}
+}
@@ -0,0 +1,619 @@
+package calc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ---- AWS Price List offer files (offline import into pricing.yaml) ----
+
+// Tenancy values of the offer files.
+const (
+	TenancyShared    = "Shared"
+	TenancyDedicated = "Dedicated"
+	TenancyHost      = "Host"
+)
+
+// OfferFilter selects the prices imported from an offer file.
+type OfferFilter struct {
+	// Regions are region codes; empty imports every region of the file.
+	Regions []string
+	// OS are operating system keys (see ParseOperatingSystem); empty is Linux.
+	OS []string
+	// Tenancy is one of the Tenancy* values; empty is Shared.
+	Tenancy string
+	// Purchases are On-Demand and Standard Reserved Instance options; empty
+	// is On-Demand. Spot and Savings Plans are not part of EC2 offer files.
+	Purchases []PurchaseOption
+	// Instances restricts the instance types imported.
+	Instances CatalogFilter
+}
+
+// ParseTenancy normalizes a tenancy name to one of the Tenancy* values.
+func ParseTenancy(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "shared", "default":
+		return TenancyShared, nil
+	case "dedicated":
+		return TenancyDedicated, nil
+	case "host", "dedicated-host":
+		return TenancyHost, nil
+	}
+	return "", fmt.Errorf("unknown tenancy %q (use shared, dedicated or host)", s)
+}
+
+// Validate reports purchase options that offer files do not carry.
+func (f OfferFilter) Validate() error {
+	for _, p := range f.Purchases {
+		switch p.Kind {
+		case "", PurchaseOnDemand, PurchaseReserved:
+		default:
+			return fmt.Errorf("%s prices are not part of EC2 offer files; use the discounts section of pricing.yaml", p.Label())
+		}
+	}
+	if _, err := ParseTenancy(f.Tenancy); err != nil {
+		return err
+	}
+	return f.Instances.Validate()
+}
+
+// OfferImport is the result of reading an offer file.
+type OfferImport struct {
+	// Source is the file read and Publication its publication date, if any.
+	Source      string
+	Publication string
+	// Entries are the imported prices by region code, sorted by instance
+	// type, OS and purchase option.
+	Entries map[string][]pricingEntry
+}
+
+// Count is the number of imported prices.
+func (im OfferImport) Count() int {
+	n := 0
+	for _, list := range im.Entries {
+		n += len(list)
+	}
+	return n
+}
+
+// Regions lists the imported region codes.
+func (im OfferImport) Regions() []string {
+	out := make([]string, 0, len(im.Entries))
+	for r := range im.Entries {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// offerProduct holds the attributes of an offer SKU the importer uses.
+type offerProduct struct {
+	Family       string
+	InstanceType string
+	VCPU         string
+	Memory       string
+	Region       string
+	OS           string
+	PreInstalled string
+	License      string
+	Tenancy      string
+	Capacity     string
+}
+
+// offerTerm is one On-Demand or Reserved offer of a SKU, with its price
+// dimensions folded into an hourly rate and an upfront fee.
+type offerTerm struct {
+	Type    string // OnDemand or Reserved
+	Lease   string
+	Payment string
+	Class   string
+	Hourly  float64
+	Upfront float64
+}
+
+// ImportOfferFile reads a locally downloaded EC2 offer file (JSON or CSV,
+// detected from the extension or the first byte) and returns the prices
+// matching f. It never touches the network.
+func ImportOfferFile(path string, f OfferFilter) (OfferImport, error) {
+	if err := f.Validate(); err != nil {
+		return OfferImport{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return OfferImport{}, err
+	}
+	defer file.Close()
+	r := bufio.NewReaderSize(file, 1<<20)
+
+	isJSON := strings.EqualFold(filepath.Ext(path), ".json")
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" && ext != ".csv" {
+		peek, _ := r.Peek(64)
+		isJSON = bytes.HasPrefix(bytes.TrimSpace(peek), []byte("{"))
+	}
+
+	var (
+		products map[string]offerProduct
+		terms    map[string][]offerTerm
+		pub      string
+	)
+	if isJSON {
+		products, terms, pub, err = readOfferJSON(r, f)
+	} else {
+		products, terms, pub, err = readOfferCSV(r, f)
+	}
+	if err != nil {
+		return OfferImport{}, fmt.Errorf("read offer file %s: %w", path, err)
+	}
+	im := buildOfferImport(products, terms, f)
+	im.Source, im.Publication = path, pub
+	return im, nil
+}
+
+// keep reports whether the product is an EC2 instance price selected by f,
+// returning its OS key.
+func (f OfferFilter) keep(p offerProduct) (string, bool) {
+	if p.Family != "Compute Instance" || p.InstanceType == "" {
+		return "", false
+	}
+	if p.Capacity != "" && p.Capacity != "Used" {
+		return "", false
+	}
+	if p.License != "" && p.License != "No License required" {
+		return "", false
+	}
+	tenancy, _ := ParseTenancy(f.Tenancy)
+	if p.Tenancy != tenancy {
+		return "", false
+	}
+	if len(f.Regions) > 0 && !containsString(f.Regions, p.Region) {
+		return "", false
+	}
+	os, ok := offerOS(p.OS, p.PreInstalled)
+	if !ok {
+		return "", false
+	}
+	wanted := f.OS
+	if len(wanted) == 0 {
+		wanted = []string{OSLinux}
+	}
+	if !containsString(wanted, os) {
+		return "", false
+	}
+	return os, f.Instances.Allows(p.InstanceType)
+}
+
+// offerOS maps the operatingSystem and preInstalledSw attributes to an OS key.
+func offerOS(osName, sw string) (string, bool) {
+	if sw == "" {
+		sw = "NA"
+	}
+	switch {
+	case osName == "Linux" && sw == "NA":
+		return OSLinux, true
+	case osName == "RHEL" && sw == "NA":
+		return OSRHEL, true
+	case osName == "SUSE" && sw == "NA":
+		return OSSUSE, true
+	case osName == "Windows":
+		switch sw {
+		case "NA":
+			return OSWindows, true
+		case "SQL Web":
+			return OSWindowsSQLWeb, true
+		case "SQL Std":
+			return OSWindowsSQLStd, true
+		case "SQL Ent":
+			return OSWindowsSQLEnt, true
+		}
+	}
+	return "", false
+}
+
+// purchase returns the purchase option of the term, if it is importable.
+func (t offerTerm) purchase() (PurchaseOption, bool) {
+	if t.Type == "OnDemand" {
+		return PurchaseOption{Kind: PurchaseOnDemand}, t.Hourly > 0
+	}
+	if t.Type != "Reserved" || !strings.EqualFold(t.Class, "standard") {
+		return PurchaseOption{}, false
+	}
+	lease := strings.ReplaceAll(strings.ToLower(t.Lease), " ", "")
+	pay := strings.ReplaceAll(strings.ToLower(t.Payment), " ", "-")
+	p, err := ParsePurchaseOption(fmt.Sprintf("ri-%s-%s", lease, pay))
+	return p, err == nil
+}
+
+func buildOfferImport(products map[string]offerProduct, terms map[string][]offerTerm, f OfferFilter) OfferImport {
+	wanted := f.Purchases
+	if len(wanted) == 0 {
+		wanted = []PurchaseOption{{Kind: PurchaseOnDemand}}
+	}
+	type key struct{ region, name, os, purchase string }
+	best := map[key]pricingEntry{}
+	for sku, p := range products {
+		os, ok := f.keep(p)
+		if !ok {
+			continue
+		}
+		vcpus, _ := strconv.Atoi(strings.TrimSpace(p.VCPU))
+		mem := parseOfferMemory(p.Memory)
+		for _, t := range terms[sku] {
+			pur, ok := t.purchase()
+			if !ok || !containsPurchase(wanted, pur) {
+				continue
+			}
+			e := pricingEntry{
+				Name:      p.InstanceType,
+				Hourly:    t.Hourly,
+				VCpus:     vcpus,
+				MemoryGiB: mem,
+				Upfront:   t.Upfront,
+			}
+			if pur.Key() != PurchaseOnDemand {
+				e.Purchase = pur.Key()
+			}
+			if os != OSLinux {
+				e.OS = os
+			}
+			k := key{p.Region, p.InstanceType, os, pur.Key()}
+			if prev, ok := best[k]; ok && prev.Hourly+prev.Upfront <= e.Hourly+e.Upfront {
+				continue
+			}
+			best[k] = e
+		}
+	}
+	im := OfferImport{Entries: map[string][]pricingEntry{}}
+	for k, e := range best {
+		im.Entries[k.region] = append(im.Entries[k.region], e)
+	}
+	for _, list := range im.Entries {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Name != list[j].Name {
+				return list[i].Name < list[j].Name
+			}
+			if list[i].OS != list[j].OS {
+				return list[i].OS < list[j].OS
+			}
+			return list[i].Purchase < list[j].Purchase
+		})
+	}
+	return im
+}
+
+// parseOfferMemory reads values such as "8 GiB" or "1,952 GiB".
+func parseOfferMemory(s string) float64 {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", ""))
+	s = strings.TrimSpace(strings.TrimSuffix(s, "GiB"))
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+func containsString(list []string, v string) bool {
+	for _, it := range list {
+		if it == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPurchase(list []PurchaseOption, p PurchaseOption) bool {
+	for _, it := range list {
+		if it.Key() == p.Key() {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- JSON offer files ----
+
+type offerJSONProduct struct {
+	SKU           string `json:"sku"`
+	ProductFamily string `json:"productFamily"`
+	Attributes    struct {
+		InstanceType    string `json:"instanceType"`
+		VCPU            string `json:"vcpu"`
+		Memory          string `json:"memory"`
+		RegionCode      string `json:"regionCode"`
+		OperatingSystem string `json:"operatingSystem"`
+		PreInstalledSw  string `json:"preInstalledSw"`
+		LicenseModel    string `json:"licenseModel"`
+		Tenancy         string `json:"tenancy"`
+		CapacityStatus  string `json:"capacitystatus"`
+	} `json:"attributes"`
+}
+
+type offerJSONTerm struct {
+	PriceDimensions map[string]struct {
+		Unit         string            `json:"unit"`
+		PricePerUnit map[string]string `json:"pricePerUnit"`
+	} `json:"priceDimensions"`
+	TermAttributes struct {
+		LeaseContractLength string `json:"LeaseContractLength"`
+		OfferingClass       string `json:"OfferingClass"`
+		PurchaseOption      string `json:"PurchaseOption"`
+	} `json:"termAttributes"`
+}
+
+// readOfferJSON streams the offer JSON so multi-gigabyte files are not held
+// in memory: products are decoded one by one and only the EC2 instances
+// selected by f are kept, and so are the terms of those SKUs.
+func readOfferJSON(r io.Reader, f OfferFilter) (map[string]offerProduct, map[string][]offerTerm, string, error) {
+	dec := json.NewDecoder(r)
+	products := map[string]offerProduct{}
+	terms := map[string][]offerTerm{}
+	var pub string
+	seenProducts := false
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, nil, "", err
+	}
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		switch key {
+		case "publicationDate":
+			if err := dec.Decode(&pub); err != nil {
+				return nil, nil, "", err
+			}
+		case "products":
+			err = eachEntry(dec, func(sku string) error {
+				var p offerJSONProduct
+				if err := dec.Decode(&p); err != nil {
+					return err
+				}
+				a := p.Attributes
+				op := offerProduct{
+					Family: p.ProductFamily, InstanceType: a.InstanceType, VCPU: a.VCPU,
+					Memory: a.Memory, Region: a.RegionCode, OS: a.OperatingSystem,
+					PreInstalled: a.PreInstalledSw, License: a.LicenseModel,
+					Tenancy: a.Tenancy, Capacity: a.CapacityStatus,
+				}
+				if _, ok := f.keep(op); ok {
+					products[sku] = op
+				}
+				return nil
+			})
+			seenProducts = true
+		case "terms":
+			err = eachEntry(dec, func(termType string) error {
+				return eachEntry(dec, func(sku string) error {
+					if _, ok := products[sku]; seenProducts && !ok {
+						return skipValue(dec)
+					}
+					var offers map[string]offerJSONTerm
+					if err := dec.Decode(&offers); err != nil {
+						return err
+					}
+					for _, o := range offers {
+						terms[sku] = append(terms[sku], o.fold(termType))
+					}
+					return nil
+				})
+			})
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return nil, nil, "", err
+		}
+	}
+	return products, terms, pub, nil
+}
+
+func (o offerJSONTerm) fold(termType string) offerTerm {
+	t := offerTerm{
+		Type:    termType,
+		Lease:   o.TermAttributes.LeaseContractLength,
+		Payment: o.TermAttributes.PurchaseOption,
+		Class:   o.TermAttributes.OfferingClass,
+	}
+	for _, d := range o.PriceDimensions {
+		t.add(d.Unit, d.PricePerUnit["USD"])
+	}
+	return t
+}
+
+// add folds one price dimension: "Hrs" is the hourly rate, "Quantity" the
+// upfront fee.
+func (t *offerTerm) add(unit, usd string) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(usd), 64)
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(unit) {
+	case "hrs", "hours":
+		t.Hourly += v
+	case "quantity":
+		t.Upfront += v
+	}
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("unexpected JSON token %v, want %q", tok, want)
+	}
+	return nil
+}
+
+func objectKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected JSON token %v, want an object key", tok)
+	}
+	return key, nil
+}
+
+// eachEntry walks the object at the decoder position, calling fn with each
+// key; fn must consume the value.
+func eachEntry(dec *json.Decoder, fn func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func skipValue(dec *json.Decoder) error {
+	var raw json.RawMessage
+	return dec.Decode(&raw)
+}
+
+// ---- CSV offer files ----
+
+// readOfferCSV reads the CSV flavour: a few metadata lines, a header starting
+// with "SKU", then one row per SKU, term and price dimension. Like
+// readOfferJSON, it keeps only the rows of EC2 instances selected by f.
+func readOfferCSV(r io.Reader, f OfferFilter) (map[string]offerProduct, map[string][]offerTerm, string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	var pub string
+	col := map[string]int{}
+	for {
+		rec, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil, "", fmt.Errorf("no header row starting with SKU")
+			}
+			return nil, nil, "", err
+		}
+		if len(rec) >= 2 && rec[0] == "Publication Date" {
+			pub = rec[1]
+		}
+		if len(rec) > 0 && rec[0] == "SKU" {
+			for i, name := range rec {
+				col[name] = i
+			}
+			break
+		}
+	}
+	for _, name := range []string{"TermType", "Unit", "PricePerUnit", "Instance Type", "Operating System", "Tenancy", "Product Family"} {
+		if _, ok := col[name]; !ok {
+			return nil, nil, "", fmt.Errorf("missing column %q", name)
+		}
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	products := map[string]offerProduct{}
+	terms := map[string][]offerTerm{}
+	index := map[string]int{} // SKU + offer term code -> position in terms[sku]
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if get(rec, "Product Family") != "Compute Instance" {
+			continue
+		}
+		sku := get(rec, "SKU")
+		if _, ok := products[sku]; !ok {
+			p := offerProduct{
+				Family:       get(rec, "Product Family"),
+				InstanceType: get(rec, "Instance Type"),
+				VCPU:         get(rec, "vCPU"),
+				Memory:       get(rec, "Memory"),
+				Region:       get(rec, "Region Code"),
+				OS:           get(rec, "Operating System"),
+				PreInstalled: get(rec, "Pre Installed S/W"),
+				License:      get(rec, "License Model"),
+				Tenancy:      get(rec, "Tenancy"),
+				Capacity:     get(rec, "CapacityStatus"),
+			}
+			if _, ok := f.keep(p); !ok {
+				continue
+			}
+			products[sku] = p
+		}
+		if get(rec, "Currency") != "" && get(rec, "Currency") != "USD" {
+			continue
+		}
+		id := sku + "." + get(rec, "OfferTermCode")
+		i, ok := index[id]
+		if !ok {
+			terms[sku] = append(terms[sku], offerTerm{
+				Type:    get(rec, "TermType"),
+				Lease:   get(rec, "LeaseContractLength"),
+				Payment: get(rec, "PurchaseOption"),
+				Class:   get(rec, "OfferingClass"),
+			})
+			i = len(terms[sku]) - 1
+			index[id] = i
+		}
+		terms[sku][i].add(get(rec, "Unit"), get(rec, "PricePerUnit"))
+	}
+	return products, terms, pub, nil
+}
+
+// ---- pricing.yaml output ----
+
+// PricingYAML renders the import as a pricing.yaml document. The filters,
+// discounts and os_surcharges sections of base (an existing pricing.yaml,
+// may be nil) are carried over.
+func (im OfferImport) PricingYAML(base []byte) ([]byte, error) {
+	var doc pricingDoc
+	if len(base) > 0 {
+		if err := yaml.Unmarshal(base, &doc); err != nil {
+			return nil, fmt.Errorf("parse existing pricing file: %w", err)
+		}
+	}
+	doc.Version = im.Publication
+	doc.Region, doc.EC2 = "", nil
+	doc.Regions = map[string]pricingRegion{}
+	for region, list := range im.Entries {
+		doc.Regions[region] = pricingRegion{EC2: list}
+	}
+	body, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Gerado por `aws-calculator-gen catalog import` a partir de %s", filepath.Base(im.Source))
+	if im.Publication != "" {
+		fmt.Fprintf(&buf, " (publicação %s)", im.Publication)
+	}
+	buf.WriteString(".\n# Preços em USD; hourly é convertido para monthly (730h) pelo planejador.\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+package calc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const offerJSON = `{
+  "formatVersion": "v1.0",
+  "offerCode": "AmazonEC2",
+  "publicationDate": "2025-01-10T00:00:00Z",
+  "products": {
+    "SKU1": {"sku": "SKU1", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "m7i.large", "vcpu": "2", "memory": "8 GiB", "regionCode": "us-east-1",
+      "operatingSystem": "Linux", "preInstalledSw": "NA", "licenseModel": "No License required",
+      "tenancy": "Shared", "capacitystatus": "Used"}},
+    "SKU2": {"sku": "SKU2", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "m7i.large", "vcpu": "2", "memory": "8 GiB", "regionCode": "us-east-1",
+      "operatingSystem": "Windows", "preInstalledSw": "SQL Std", "licenseModel": "No License required",
+      "tenancy": "Shared", "capacitystatus": "Used"}},
+    "SKU3": {"sku": "SKU3", "productFamily": "Compute Instance", "attributes": {
+      "instanceType": "m7i.large", "vcpu": "2", "memory": "8 GiB", "regionCode": "us-east-1",
+      "operatingSystem": "Linux", "preInstalledSw": "NA", "licenseModel": "No License required",
+      "tenancy": "Dedicated", "capacitystatus": "Used"}},
+    "SKU4": {"sku": "SKU4", "productFamily": "Storage", "attributes": {"regionCode": "us-east-1"}}
+  },
+  "terms": {
+    "OnDemand": {
+      "SKU1": {"SKU1.A": {"priceDimensions": {"SKU1.A.1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.1008"}}}, "termAttributes": {}}},
+      "SKU2": {"SKU2.A": {"priceDimensions": {"SKU2.A.1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.6728"}}}, "termAttributes": {}}},
+      "SKU3": {"SKU3.A": {"priceDimensions": {"SKU3.A.1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.111"}}}, "termAttributes": {}}},
+      "SKU4": {"SKU4.A": {"priceDimensions": {"SKU4.A.1": {"unit": "GB-Mo", "pricePerUnit": {"USD": "0.08"}}}, "termAttributes": {}}}
+    },
+    "Reserved": {
+      "SKU1": {
+        "SKU1.B": {"priceDimensions": {
+            "SKU1.B.1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0300"}},
+            "SKU1.B.2": {"unit": "Quantity", "pricePerUnit": {"USD": "262"}}},
+          "termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "standard", "PurchaseOption": "Partial Upfront"}},
+        "SKU1.C": {"priceDimensions": {
+            "SKU1.C.1": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0500"}}},
+          "termAttributes": {"LeaseContractLength": "1yr", "OfferingClass": "convertible", "PurchaseOption": "No Upfront"}}
+      }
+    }
+  }
+}`
+
+const offerCSV = `"FormatVersion","v1.0"
+"Disclaimer","This pricing list is for informational purposes only."
+"Publication Date","2025-01-10T00:00:00Z"
+"Version","20250110000000"
+"OfferCode","AmazonEC2"
+"SKU","OfferTermCode","RateCode","TermType","PriceDescription","EffectiveDate","StartingRange","EndingRange","Unit","PricePerUnit","Currency","LeaseContractLength","PurchaseOption","OfferingClass","Product Family","serviceCode","Location","Location Type","Instance Type","vCPU","Memory","Tenancy","Operating System","License Model","Pre Installed S/W","CapacityStatus","Region Code"
+"SKU1","A","SKU1.A.1","OnDemand","","2025-01-01","0","Inf","Hrs","0.1008","USD","","","","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m7i.large","2","8 GiB","Shared","Linux","No License required","NA","Used","us-east-1"
+"SKU1","B","SKU1.B.1","Reserved","","2025-01-01","0","Inf","Hrs","0.03","USD","1yr","Partial Upfront","standard","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m7i.large","2","8 GiB","Shared","Linux","No License required","NA","Used","us-east-1"
+"SKU1","B","SKU1.B.2","Reserved","","2025-01-01","0","Inf","Quantity","262","USD","1yr","Partial Upfront","standard","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m7i.large","2","8 GiB","Shared","Linux","No License required","NA","Used","us-east-1"
+"SKU5","A","SKU5.A.1","OnDemand","","2025-01-01","0","Inf","Hrs","0.2016","USD","","","","Compute Instance","AmazonEC2","US East (N. Virginia)","AWS Region","m7i.xlarge","4","16 GiB","Shared","Linux","No License required","NA","Used","sa-east-1"
+`
+
+func writeOffer(t *testing.T, name, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestImportOfferJSON(t *testing.T) {
+	ri, _ := ParsePurchaseOption("ri-1yr-partial-upfront")
+	im, err := ImportOfferFile(writeOffer(t, "offer.json", offerJSON), OfferFilter{
+		Regions:   []string{"us-east-1"},
+		OS:        []string{OSLinux, OSWindowsSQLStd},
+		Purchases: []PurchaseOption{{Kind: PurchaseOnDemand}, ri},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := im.Entries["us-east-1"]
+	if len(list) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", list)
+	}
+	want := []pricingEntry{
+		{Name: "m7i.large", Hourly: 0.1008, VCpus: 2, MemoryGiB: 8},
+		{Name: "m7i.large", Hourly: 0.03, Upfront: 262, VCpus: 2, MemoryGiB: 8, Purchase: "ri-1yr-partial-upfront"},
+		{Name: "m7i.large", Hourly: 0.6728, VCpus: 2, MemoryGiB: 8, OS: OSWindowsSQLStd},
+	}
+	for i, w := range want {
+		if list[i] != w {
+			t.Errorf("entry %d = %+v, want %+v", i, list[i], w)
+		}
+	}
+	if im.Publication != "2025-01-10T00:00:00Z" {
+		t.Errorf("publication = %q", im.Publication)
+	}
+}
+
+func TestReadOfferJSONKeepsSelectedInstances(t *testing.T) {
+	f := OfferFilter{Regions: []string{"us-east-1"}}
+	products, terms, _, err := readOfferJSON(strings.NewReader(offerJSON), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SKU2 runs SQL Server, SKU3 is dedicated and SKU4 is storage.
+	if _, ok := products["SKU1"]; !ok || len(products) != 1 {
+		t.Errorf("products = %+v, want SKU1 only", products)
+	}
+	if len(terms["SKU1"]) != 3 || len(terms) != 1 {
+		t.Errorf("terms = %+v, want the 3 terms of SKU1 only", terms)
+	}
+}
+
+func TestImportOfferCSV(t *testing.T) {
+	im, err := ImportOfferFile(writeOffer(t, "offer.csv", offerCSV), OfferFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := im.Regions(); strings.Join(got, ",") != "sa-east-1,us-east-1" {
+		t.Fatalf("regions = %v", got)
+	}
+	if e := im.Entries["sa-east-1"]; len(e) != 1 || e[0].Name != "m7i.xlarge" || e[0].MemoryGiB != 16 || e[0].VCpus != 4 {
+		t.Fatalf("unexpected sa-east-1 entries: %+v", e)
+	}
+	if e := im.Entries["us-east-1"]; len(e) != 1 || e[0].Purchase != "" {
+		t.Fatalf("expected On-Demand only, got %+v", e)
+	}
+}
+
+func TestOfferImportPricingYAML(t *testing.T) {
+	im, err := ImportOfferFile(writeOffer(t, "offer.json", offerJSON), OfferFilter{Tenancy: "dedicated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := []byte("discounts:\n  spot: 0.6\nregions:\n  eu-west-1:\n    ec2:\n      - name: old.large\n        hourly: 1\n")
+	out, err := im.PricingYAML(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cat := parsePricingYAML(out)
+	if cat.Discounts["spot"] != 0.6 {
+		t.Errorf("discounts not kept: %s", out)
+	}
+	if cat.Info.Version != im.Publication {
+		t.Errorf("version %q, want the publication date %q", cat.Info.Version, im.Publication)
+	}
+	if _, ok := cat.Regions["eu-west-1"]; ok {
+		t.Errorf("old prices must be replaced: %s", out)
+	}
+	opts, err := cat.Options("us-east-1", OSLinux, PurchaseOption{}, 0)
+	if err != nil || len(opts) != 1 || opts[0].Hourly != 0.111 {
+		t.Fatalf("unexpected dedicated prices %+v (%v)\n%s", opts, err, out)
+	}
+}
+
+func TestOfferFilterValidate(t *testing.T) {
+	sp, _ := ParsePurchaseOption("compute-sp-1yr")
+	if err := (OfferFilter{Purchases: []PurchaseOption{sp}}).Validate(); err == nil {
+		t.Fatal("expected error for Savings Plans")
+	}
+	if err := (OfferFilter{Tenancy: "shared-ish"}).Validate(); err == nil {
+		t.Fatal("expected error for unknown tenancy")
+	}
+}
@@ -0,0 +1,274 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// ---- Page object: the calculator UI behind an interface ----
+
+// CalculatorPage drives the AWS Pricing Calculator UI. Orchestrator.Run only
+// talks to the calculator through it, so the orchestration (plan iteration,
+// error handling, retries) can be tested with a fake page. Each step gives up
+// when its ctx is done.
+type CalculatorPage interface {
+	// Open loads the calculator served at baseURL for the region, ready to
+	// find a service.
+	Open(ctx context.Context, baseURL, region string) error
+	// OpenEC2Configurator finds EC2 in the service finder and opens its
+	// configurator.
+	OpenEC2Configurator(ctx context.Context) error
+	// SetInstance configures the open EC2 configurator for one plan item:
+	// operating system, purchase option, usage, count and instance type.
+	SetInstance(ctx context.Context, it PlanItem, p PurchaseOption, hpm float64) error
+	// SaveAndAdd saves the configured service and returns to the service
+	// finder.
+	SaveAndAdd(ctx context.Context) error
+	// ViewSummary opens the estimate summary.
+	ViewSummary(ctx context.Context) error
+	// ReadSummary reads the totals and service rows of the open estimate
+	// summary, as the calculator computed them.
+	ReadSummary(ctx context.Context) (Summary, error)
+	// Rename sets the estimate name.
+	Rename(ctx context.Context, name string) error
+	// Share publishes the estimate and returns its public link.
+	Share(ctx context.Context) (string, error)
+	// Snapshot records the page for troubleshooting.
+	Snapshot(note string)
+	// Close releases the browser.
+	Close()
+}
+
+// chromePage is the CalculatorPage driving Chrome through chromedp. It finds
+// the calculator's controls through the selector pack sp and retries the
+// clicks and reads within a step with the policies in retry.
+type chromePage struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	sp     *SelectorPack
+	retry  map[string]RetryPolicy
+}
+
+// pageConfig is how Run opens the calculator page.
+type pageConfig struct {
+	headful   bool
+	browser   BrowserConfig
+	selectors *SelectorPack
+	// retry are the resolved retry policies of the run.
+	retry map[string]RetryPolicy
+	// artifacts, when set, receives the page's snapshots.
+	artifacts *runArtifacts
+}
+
+// newChromePage opens a tab in the configured browser.
+func newChromePage(ctx context.Context, cfg pageConfig) (CalculatorPage, error) {
+	if cfg.browser.Remote() {
+		logger(ctx).Info("connecting to Chrome", "url", cfg.browser.RemoteURL)
+	} else {
+		logger(ctx).Info("launching Chrome", "headful", cfg.headful, "profile", cfg.browser.UserDataDir)
+	}
+	bctx, cancel := newBrowser(ctx, cfg.headful, cfg.browser)
+	bctx = withArtifacts(bctx, cfg.artifacts)
+	return &chromePage{ctx: bctx, sp: cfg.selectors, retry: cfg.retry, cancel: cancel}, nil
+}
+
+// step returns the browser context bound to the deadline, cancellation and
+// logger of a step's ctx. Ending it aborts the step's actions, not the
+// browser.
+func (p *chromePage) step(ctx context.Context) (context.Context, context.CancelFunc) {
+	bctx, cancel := context.WithCancel(withLogger(p.ctx, logger(ctx)))
+	stop := context.AfterFunc(ctx, cancel)
+	if d, ok := ctx.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		bctx, cancelDeadline = context.WithDeadline(bctx, d)
+		return bctx, func() {
+			stop()
+			cancelDeadline()
+			cancel()
+		}
+	}
+	return bctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// policy is the retry policy of step, or a single attempt.
+func (p *chromePage) policy(step string) RetryPolicy {
+	if pol, ok := p.retry[step]; ok {
+		return pol
+	}
+	if pol, ok := DefaultRetryPolicies[step]; ok {
+		return pol
+	}
+	return RetryPolicy{Attempts: 1}
+}
+
+func (p *chromePage) Open(ctx context.Context, baseURL, region string) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	navURL := buildURL(baseURL, region, "")
+	logger(ctx).Info("navigating", "url", navURL)
+	if err := chromedp.Run(ctx, chromedp.Navigate(navURL)); err != nil {
+		return err
+	}
+	dismissCookieBanner(ctx)
+
+	// "Create estimate" só aparece na landing page.
+	_ = clickAny(ctx, p.sp.el("create_estimate"))
+	logger(ctx).Debug("waiting for the service finder", "url", currentURL(ctx))
+	return waitElement(ctx, p.sp.el("find_service"), 0)
+}
+
+func (p *chromePage) OpenEC2Configurator(ctx context.Context) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	if _, ok := findNow(ctx, p.sp.el("ec2_config_header")); ok {
+		logger(ctx).Info("EC2 configurator already open", "url", currentURL(ctx))
+		return nil
+	}
+	logger(ctx).Info("searching service", "service", "EC2")
+	_ = waitElement(ctx, p.sp.el("find_service"), 5*time.Second)
+	finder, ok := findNow(ctx, p.sp.el("find_service"))
+	if !ok {
+		return fmt.Errorf("service finder not found")
+	}
+	if err := typeInto(ctx, finder.s, finder.by, "EC2"); err != nil {
+		logger(ctx).Debug("typing failed, setting the value via JS", "selector", finder.s, "err", err)
+		if err2 := setInputValueJS(ctx, finder.s, finder.by, "EC2"); err2 != nil {
+			return fmt.Errorf("cannot type 'EC2': %v / fb: %v", err, err2)
+		}
+		_ = chromedp.Run(ctx, chromedp.SendKeys(finder.s, kb.Enter, queryOpts(finder.by)...))
+	}
+	_ = chromedp.Run(ctx, chromedp.Sleep(200*time.Millisecond))
+
+	if !clickAny(ctx, p.sp.el("ec2_configure")) {
+		_ = chromedp.Run(ctx, chromedp.SendKeys(finder.s, kb.Enter, queryOpts(finder.by)...))
+		if !clickAny(ctx, p.sp.el("ec2_configure")) {
+			return fmt.Errorf("could not find 'Configure Amazon EC2'")
+		}
+	}
+	logger(ctx).Info("EC2 configurator opened", "url", currentURL(ctx))
+	dismissCookieBanner(ctx)
+	_ = waitElement(ctx, p.sp.el("ec2_config_header"), 5*time.Second)
+	_ = waitElement(ctx, p.sp.el("instance_count"), 5*time.Second)
+	return nil
+}
+
+func (p *chromePage) SetInstance(ctx context.Context, it PlanItem, po PurchaseOption, hpm float64) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	logger(ctx).Info("configuring instance", "count", it.Count, "instance", it.Name, "os", it.OS, "purchase", po.Label())
+	_ = ensureAnyFilters(ctx, p.sp, 5*time.Second)
+	if err := ensureOperatingSystem(ctx, p.sp, it.OS, 5*time.Second); err != nil {
+		return fmt.Errorf("could not select operating system for %q: %w", it.Name, err)
+	}
+	_ = ensurePurchaseOption(ctx, p.sp, po, 5*time.Second)
+	if err := ensureUsage(ctx, p.sp, it.Hours, hpm, 5*time.Second); err != nil {
+		logger(ctx).Warn("could not set usage", "instance", it.Name, "err", err)
+	}
+	if err := setInstanceCount(ctx, p.sp, it.Count); err != nil {
+		return fmt.Errorf("could not set count for %q: %w", it.Name, err)
+	}
+	if err := selectInstanceByName(ctx, p.sp, it.Name, 5*time.Second); err != nil {
+		return fmt.Errorf("could not select instance %q: %w", it.Name, err)
+	}
+	return nil
+}
+
+func (p *chromePage) SaveAndAdd(ctx context.Context) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	return clickSaveAndAddService(ctx, p.sp, p.policy(StepSaveClick))
+}
+
+func (p *chromePage) ViewSummary(ctx context.Context) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	if err := clickViewSummary(ctx, p.sp); err != nil {
+		return err
+	}
+	_ = waitElement(ctx, p.sp.el("share_button"), 5*time.Second)
+	return nil
+}
+
+func (p *chromePage) ReadSummary(ctx context.Context) (Summary, error) {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	if err := waitElement(ctx, p.sp.el("summary_monthly"), 10*time.Second); err != nil {
+		return Summary{}, err
+	}
+	return readSummary(ctx, p.sp)
+}
+
+func (p *chromePage) Rename(ctx context.Context, name string) error {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	logger(ctx).Info("renaming estimate", "name", name)
+	clickAny(ctx, p.sp.el("edit_name"))
+	input, ok := find(ctx, p.sp.el("name_input"))
+	if !ok {
+		return fmt.Errorf("estimate name input not found")
+	}
+	if err := typeInto(ctx, input.s, input.by, name); err != nil {
+		return err
+	}
+	clickAny(ctx, p.sp.el("save_name"))
+	return nil
+}
+
+func (p *chromePage) Share(ctx context.Context) (string, error) {
+	ctx, cancel := p.step(ctx)
+	defer cancel()
+	logger(ctx).Info("opening the Share dialog")
+	_ = scrollToTop(ctx)
+	if err := clickElement(ctx, p.sp.el("share_button"), 0); err != nil {
+		return "", fmt.Errorf("could not open Share dialog/button: %w", err)
+	}
+	dismissCookieBanner(ctx)
+
+	shareURL := handleShareConsent(ctx, p.sp)
+
+	// 10) Buscar link (política share_link; reabrir Share se necessário)
+	if !looksLikeShareURL(shareURL) {
+		_ = p.policy(StepShareLink).do(ctx, StepShareLink, nil, func(ctx context.Context) error {
+			if shareURL = getShareURLFromInputs(ctx, p.sp); !looksLikeShareURL(shareURL) {
+				return fmt.Errorf("public link not shown yet")
+			}
+			return nil
+		})
+	}
+	if !looksLikeShareURL(shareURL) {
+		// reabrir Share e tentar de novo rapidamente
+		_ = clickElement(ctx, p.sp.el("share_button"), 3*time.Second)
+		_ = chromedp.Run(ctx, chromedp.Sleep(800*time.Millisecond))
+		shareURL = getShareURLFromInputs(ctx, p.sp)
+	}
+	if !looksLikeShareURL(shareURL) {
+		shareURL = getShareURLAnywhere(ctx, p.sp)
+	}
+	if !looksLikeShareURL(shareURL) {
+		shareURL = waitForShareLink(ctx, p.sp, 3, 300*time.Millisecond)
+	}
+	p.Snapshot("(final snapshot)")
+	if strings.TrimSpace(shareURL) == "" {
+		where := "tmp.html"
+		if a := artifactsFrom(ctx); a != nil {
+			where = "the snapshots in " + a.dir
+		}
+		return "", fmt.Errorf("share link did not appear; see %s", where)
+	}
+	return shareURL, nil
+}
+
+func (p *chromePage) Snapshot(note string) { dumpHTML(p.ctx, note) }
+
+func (p *chromePage) Close() {
+	p.Snapshot("(always end)")
+	p.cancel()
+}
@@ -0,0 +1,200 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakePage is an in-memory CalculatorPage. It records the calls it gets and
+// fails the calls listed in fail (by method name, or "Method:item") as many
+// times as given; the calls listed in hang block until their step times out.
+type fakePage struct {
+	calls    []string
+	services []PlanItem
+	name     string
+	fail     map[string]int
+	hang     map[string]int
+	closed   bool
+	// summary, when set, is what ReadSummary reads instead of the services
+	// configured.
+	summary *Summary
+	// configuring is set while the EC2 configurator is open, where the
+	// service finder is gone.
+	configuring bool
+}
+
+func (p *fakePage) call(ctx context.Context, name string, args ...any) error {
+	c := name
+	if len(args) > 0 {
+		c += ":" + fmt.Sprint(args...)
+	}
+	p.calls = append(p.calls, c)
+	for _, key := range []string{name, c} {
+		if p.hang[key] > 0 {
+			p.hang[key]--
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if p.fail[key] > 0 {
+			p.fail[key]--
+			return errors.New(c + " failed")
+		}
+	}
+	return nil
+}
+
+func (p *fakePage) Open(ctx context.Context, baseURL, region string) error {
+	p.configuring = false
+	return p.call(ctx, "Open", baseURL+" "+region)
+}
+func (p *fakePage) OpenEC2Configurator(ctx context.Context) error {
+	if err := p.call(ctx, "OpenEC2Configurator"); err != nil {
+		return err
+	}
+	if p.configuring {
+		return errors.New("service finder not found")
+	}
+	p.configuring = true
+	return nil
+}
+func (p *fakePage) ViewSummary(ctx context.Context) error { return p.call(ctx, "ViewSummary") }
+func (p *fakePage) ReadSummary(ctx context.Context) (Summary, error) {
+	if err := p.call(ctx, "ReadSummary"); err != nil {
+		return Summary{}, err
+	}
+	if p.summary != nil {
+		return *p.summary, nil
+	}
+	// Like the calculator, rows and totals show the recurring monthly cost
+	// and the upfront fees apart.
+	var s Summary
+	for _, it := range p.services {
+		m := float64(it.Count) * it.Recurring
+		s.Services = append(s.Services, SummaryService{Config: fmt.Sprintf("Amazon EC2 %d x %s", it.Count, it.Name), Monthly: m})
+		s.Monthly += m
+		s.Upfront += float64(it.Count) * it.Upfront
+	}
+	return s, nil
+}
+func (p *fakePage) Snapshot(note string) {}
+func (p *fakePage) Close()               { p.closed = true }
+func (p *fakePage) SaveAndAdd(ctx context.Context) error {
+	if err := p.call(ctx, "SaveAndAdd"); err != nil {
+		return err
+	}
+	p.configuring = false
+	return nil
+}
+func (p *fakePage) Rename(ctx context.Context, name string) error {
+	p.name = name
+	return p.call(ctx, "Rename")
+}
+func (p *fakePage) Share(ctx context.Context) (string, error) {
+	if err := p.call(ctx, "Share"); err != nil {
+		return "", err
+	}
+	return "https://calculator.aws/#/estimate?id=fake", nil
+}
+
+func (p *fakePage) SetInstance(ctx context.Context, it PlanItem, po PurchaseOption, hpm float64) error {
+	if err := p.call(ctx, "SetInstance", it.Name); err != nil {
+		return err
+	}
+	p.services = append(p.services, it)
+	return nil
+}
+
+func fakeOrchestrator(page *fakePage) Orchestrator {
+	return Orchestrator{
+		EstimateName: "MAP • ACME",
+		RegionCode:   "us-east-1",
+		MaxRetries:   1,
+		Retry:        quickRetry(),
+		Items: []PlanItem{
+			{Name: "m7g.large", Count: 2, Recurring: 60, Monthly: 60},
+			{Name: "c7g.xlarge", Count: 1, Recurring: 100, Monthly: 100},
+		},
+		openPage: func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) { return page, nil },
+	}
+}
+
+func TestRunWithFakePage(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{}
+	o := fakeOrchestrator(page)
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Open:https://calculator.aws us-east-1",
+		"OpenEC2Configurator", "SetInstance:m7g.large", "SaveAndAdd",
+		"OpenEC2Configurator", "SetInstance:c7g.xlarge", "SaveAndAdd",
+		"ViewSummary", "ReadSummary", "Rename", "Share",
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Fatalf("calls = %v\nwant    %v", page.calls, want)
+	}
+	if res.ShareURL == "" || res.AchievedMRR != 220 || len(page.services) != 2 || page.name != "MAP • ACME" || !page.closed || res.Selectors.Source != EmbeddedSelectorsSource {
+		t.Fatalf("unexpected result %+v / page %+v", res, page)
+	}
+}
+
+func TestRunRetriesItem(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{fail: map[string]int{"SetInstance:c7g.xlarge": 1, "Rename": 1}}
+	o := fakeOrchestrator(page)
+	if _, err := o.Run(context.Background()); err != nil {
+		t.Fatalf("expected the retry to recover: %v", err)
+	}
+	if n := strings.Count(strings.Join(page.calls, ","), "SetInstance:c7g.xlarge"); n != 2 {
+		t.Errorf("c7g.xlarge configured %d times, want 2: %v", n, page.calls)
+	}
+	// The configurator stays open after SetInstance fails: the retry does
+	// not search for EC2 again.
+	want := []string{"OpenEC2Configurator", "SetInstance:c7g.xlarge", "SetInstance:c7g.xlarge", "SaveAndAdd"}
+	if got := page.calls[4:8]; !reflect.DeepEqual(got, want) {
+		t.Errorf("retry calls = %v, want %v", got, want)
+	}
+
+	// A failed rename is not fatal, a failed save is and is not retried.
+	page = &fakePage{fail: map[string]int{"SaveAndAdd": 5}}
+	o = fakeOrchestrator(page)
+	_, err := o.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "save/add EC2 m7g.large") {
+		t.Fatalf("expected save error, got %v", err)
+	}
+	if strings.Count(strings.Join(page.calls, ","), "SaveAndAdd") != 1 || !page.closed {
+		t.Errorf("unexpected calls %v (closed %v)", page.calls, page.closed)
+	}
+
+	// Retries are bounded by MaxRetries.
+	page = &fakePage{fail: map[string]int{"OpenEC2Configurator": 2}}
+	o = fakeOrchestrator(page)
+	if _, err := o.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "planned item 1") {
+		t.Fatalf("expected configurator error, got %v", err)
+	}
+}
+
+// chdirTemp runs the test in a temporary directory, where Run writes its log.
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	// Run reports the pricing catalog and the selector pack; the embedded
+	// ones are enough here.
+	t.Setenv("EC2_PRICING_YAML", "")
+	t.Setenv("CALC_SELECTORS_YAML", "")
+}
@@ -0,0 +1,455 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ---- Planning (bounded subset-sum over the EC2 catalog) ----
+
+// defaultTolerance is applied when the caller does not set a tolerance.
+const defaultTolerance = 0.03
+
+// maxPlanStates bounds the size of the DP table. Monthly prices are quantized
+// so that the upper end of the tolerance window fits in this many buckets.
+const maxPlanStates = 20000
+
+// maxPlanChoices bounds the table planEC2 keeps to rebuild plans: one count
+// per option, type layer and bucket (16 MiB). Larger option sets are thinned
+// to price points spread over the catalog before planning.
+const maxPlanChoices = 1 << 23
+
+// PlanItem is one line of a plan: Count instances of the EC2 type Name.
+// Monthly is the effective monthly cost per instance (Recurring plus Upfront
+// amortized over the commitment term).
+type PlanItem struct {
+	Name      string
+	OS        string
+	Hourly    float64
+	Recurring float64
+	Upfront   float64
+	Monthly   float64
+	Count     int
+	VCPUs     int
+	MemoryGiB float64
+	// Schedule names the usage schedule of the item and Hours its monthly
+	// usage; Hours 0 means 24x7.
+	Schedule string
+	Hours    float64
+}
+
+// PlanConstraints restricts the shape of a plan. Zero values mean "no limit".
+type PlanConstraints struct {
+	// MaxTypes caps the number of distinct instance types in the plan.
+	MaxTypes int
+	// MinCount and MaxCount bound the count of every type that is used.
+	MinCount int
+	MaxCount int
+	// Multiple forces every count to a multiple of N (e.g. 2 or 3 for multi-AZ HA).
+	Multiple int
+}
+
+// Validate reports inconsistent constraint values.
+func (c PlanConstraints) Validate() error {
+	switch {
+	case c.MaxTypes < 0, c.MinCount < 0, c.MaxCount < 0, c.Multiple < 0:
+		return fmt.Errorf("plan constraints must not be negative: %+v", c)
+	case c.MaxCount > 0 && c.MinCount > c.MaxCount:
+		return fmt.Errorf("min count %d is greater than max count %d", c.MinCount, c.MaxCount)
+	}
+	lo, hi := c.countRange()
+	if hi > 0 && lo > hi {
+		return fmt.Errorf("no count between %d and %d is a multiple of %d", c.MinCount, c.MaxCount, c.Multiple)
+	}
+	return nil
+}
+
+// countRange returns the smallest and largest count allowed per type and the
+// step between allowed counts. hi == 0 means unbounded.
+func (c PlanConstraints) countRange() (lo, hi int) {
+	step := c.step()
+	lo = c.MinCount
+	if lo < 1 {
+		lo = 1
+	}
+	lo = (lo + step - 1) / step * step
+	if c.MaxCount > 0 {
+		hi = c.MaxCount / step * step
+	}
+	return lo, hi
+}
+
+func (c PlanConstraints) step() int {
+	if c.Multiple > 1 {
+		return c.Multiple
+	}
+	return 1
+}
+
+// planEC2 searches combinations of catalog options whose monthly total lands
+// within tol (relative) of targetMRR while respecting the plan constraints.
+// Among the combinations that fit, the one closest to the target wins; ties
+// are broken by the smaller instance count.
+func planEC2(opts []ec2Option, targetMRR, tol float64, cons PlanConstraints) ([]PlanItem, error) {
+	if targetMRR <= 0 {
+		return nil, fmt.Errorf("target MRR must be positive (got %.2f)", targetMRR)
+	}
+	if len(opts) == 0 {
+		return nil, fmt.Errorf("EC2 catalog is empty; nothing to plan with")
+	}
+	if err := cons.Validate(); err != nil {
+		return nil, err
+	}
+	tol = tolOrDefault(tol)
+	lower := targetMRR * (1 - tol)
+	upper := targetMRR * (1 + tol)
+
+	unit := math.Max(0.01, upper/maxPlanStates)
+	size := int(upper / unit)
+	lo, hi := cons.countRange()
+	step := cons.step()
+
+	// Quantized prices; options whose smallest allowed count already exceeds
+	// the window are dropped.
+	type quant struct {
+		opt ec2Option
+		q   int
+	}
+	var qs []quant
+	for _, o := range opts {
+		if o.Monthly <= 0 {
+			continue
+		}
+		q := int(math.Round(o.Monthly / unit))
+		if q < 1 {
+			q = 1
+		}
+		if q*lo > size {
+			continue
+		}
+		qs = append(qs, quant{opt: o, q: q})
+	}
+	if len(qs) == 0 {
+		return nil, fmt.Errorf("every EC2 option (x%d) costs more than the target MRR %.2f (+%.1f%%)", lo, targetMRR, tol*100)
+	}
+
+	// Options in the same bucket are interchangeable for the DP: keep the
+	// first (cheapest, as the catalog is sorted) of each.
+	sort.SliceStable(qs, func(i, j int) bool { return qs[i].q < qs[j].q })
+	uniq := qs[:1]
+	for _, q := range qs[1:] {
+		if q.q != uniq[len(uniq)-1].q {
+			uniq = append(uniq, q)
+		}
+	}
+	qs = uniq
+
+	// Layered DP over option types. Layer k counts distinct types used when
+	// MaxTypes is set; otherwise a single layer is kept.
+	layers := func(n int) int {
+		if cons.MaxTypes > 0 {
+			return min(cons.MaxTypes, n) + 1
+		}
+		return 1
+	}
+	width := size + 1
+
+	// Keep the choice table within maxPlanChoices by planning on evenly
+	// spaced price points of the options.
+	keep := len(qs)
+	for keep > 1 && keep*layers(keep)*width > maxPlanChoices {
+		keep--
+	}
+	if keep < len(qs) {
+		spread := make([]quant, keep)
+		for i := range spread {
+			spread[i] = qs[i*(len(qs)-1)/max(keep-1, 1)]
+		}
+		qs = spread
+	}
+	kdim := layers(len(qs))
+	prev := make([]int32, kdim*width) // fewest instances reaching (k, s)
+	next := make([]int32, kdim*width)
+	for i := range prev {
+		prev[i] = -1
+	}
+	prev[0] = 0
+	// choice[t][k*width+s] = count of type t taken to reach (k, s) after
+	// layer t; counts never exceed width.
+	choice := make([][]uint16, len(qs))
+	run := make([]int32, width) // best value using type t, for the current k
+	runCnt := make([]int32, width)
+
+	for t, q := range qs {
+		ch := make([]uint16, kdim*width)
+		copy(next, prev)
+		for k := 0; k < kdim; k++ {
+			kp := k
+			if kdim > 1 {
+				if k == 0 {
+					continue
+				}
+				kp = k - 1
+			}
+			for s := 0; s < width; s++ {
+				run[s], runCnt[s] = -1, 0
+				// start a run of this type with the smallest allowed count
+				if d := s - lo*q.q; d >= 0 && prev[kp*width+d] >= 0 {
+					run[s], runCnt[s] = prev[kp*width+d]+int32(lo), int32(lo)
+				}
+				// or extend a run by one more step
+				if d := s - step*q.q; d >= 0 && run[d] >= 0 && (hi == 0 || int(runCnt[d])+step <= hi) {
+					if v := run[d] + int32(step); run[s] < 0 || v < run[s] {
+						run[s], runCnt[s] = v, runCnt[d]+int32(step)
+					}
+				}
+				if run[s] < 0 {
+					continue
+				}
+				if i := k*width + s; next[i] < 0 || run[s] < next[i] {
+					next[i] = run[s]
+					ch[i] = uint16(runCnt[s])
+				}
+			}
+		}
+		choice[t] = ch
+		prev, next = next, prev
+	}
+	final := prev
+
+	rebuild := func(k, s int) ([]PlanItem, float64) {
+		var items []PlanItem
+		total := 0.0
+		for t := len(qs) - 1; t >= 0; t-- {
+			c := int(choice[t][k*width+s])
+			if c == 0 {
+				continue
+			}
+			o := qs[t].opt
+			items = append(items, o.item(c))
+			total += float64(c) * o.Monthly
+			s -= c * qs[t].q
+			if kdim > 1 {
+				k--
+			}
+		}
+		return items, total
+	}
+
+	// Quantization drifts by at most unit/2 per instance, so scan a little
+	// below the window and judge each candidate by its real total.
+	start := int(lower/unit) - size/50
+	if start < 1 {
+		start = 1
+	}
+	var (
+		plan      []PlanItem
+		planErr   = math.Inf(1)
+		planCount int32
+		closest   float64
+	)
+	for k := 0; k < kdim; k++ {
+		for s := start; s <= size; s++ {
+			n := final[k*width+s]
+			if n <= 0 {
+				continue
+			}
+			items, total := rebuild(k, s)
+			if math.Abs(total-targetMRR) < math.Abs(closest-targetMRR) {
+				closest = total
+			}
+			if total < lower || total > upper {
+				continue
+			}
+			e := math.Abs(total - targetMRR)
+			if e < planErr || (e == planErr && n < planCount) {
+				plan, planErr, planCount = items, e, n
+			}
+		}
+	}
+	if plan == nil {
+		for s := start - 1; closest == 0 && s > 0; s-- {
+			for k := 0; k < kdim; k++ {
+				if final[k*width+s] > 0 {
+					_, closest = rebuild(k, s)
+					break
+				}
+			}
+		}
+		return nil, fmt.Errorf("no EC2 combination lands within %.1f%% of target MRR %.2f with constraints %+v (closest found: %.2f)", tol*100, targetMRR, cons, closest)
+	}
+	return compactPlan(plan), nil
+}
+
+// plan loads the catalog, applies the filters and runs the planner that
+// matches the orchestrator's target (workload shape or MRR). A preselected
+// plan (Orchestrator.Items) is returned as is.
+func (o *Orchestrator) plan() ([]PlanItem, error) {
+	if len(o.Items) > 0 {
+		o.logPlan(o.Items)
+		return o.Items, nil
+	}
+	opts, hpm, err := o.options()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := o.solve(opts, hpm, o.Constraints)
+	if err != nil {
+		return nil, err
+	}
+	o.logPlan(plan)
+	return plan, nil
+}
+
+// options returns the catalog options priced for the run and allowed by the
+// merged catalog filters, with the hours of a 24x7 month (the run's
+// HoursPerMonth, else the catalog's).
+func (o *Orchestrator) options() ([]ec2Option, float64, error) {
+	l := o.logger()
+	cat, err := ec2Catalog()
+	if err != nil {
+		return nil, 0, err
+	}
+	l.Debug("catalog loaded", "source", cat.Info.Source, "version", cat.Info.Version, "entries", cat.size(), "regions", cat.regionCodes())
+	for _, ig := range cat.Ignored {
+		l.Warn("catalog entry ignored", "entry", ig)
+	}
+	hpm := cat.HoursPerMonth
+	if o.HoursPerMonth > 0 {
+		hpm = o.HoursPerMonth
+	}
+	priced, err := cat.Options(o.RegionCode, o.OS, o.Purchase, hpm)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter := cat.Filter.Merge(o.Filter)
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+	opts := filter.Apply(priced)
+	l.Debug("catalog filtered", "filter", fmt.Sprintf("%+v", filter), "kept", len(opts), "of", len(priced), "region", o.RegionCode)
+	if len(opts) == 0 {
+		return nil, 0, fmt.Errorf("catalog filters %+v exclude every EC2 option in %s", filter, o.RegionCode)
+	}
+	return opts, hpm, nil
+}
+
+// solve runs the planner matching the target over opts. With usage schedules
+// each schedule's share of the target is planned separately, on prices for
+// the schedule's hours, and the items are tagged with their schedule.
+func (o *Orchestrator) solve(opts []ec2Option, hpm float64, cons PlanConstraints) ([]PlanItem, error) {
+	schedules := o.Schedules
+	if len(schedules) == 0 {
+		schedules = []Schedule{{Share: 1}}
+	}
+	var plan []PlanItem
+	for _, s := range schedules {
+		hours := s.Hours(hpm)
+		priced := reprice(opts, o.Purchase, hours, hpm)
+		var items []PlanItem
+		var err error
+		if o.Workload != nil {
+			items, err = planByShape(priced, o.Workload.scaled(s.Share), cons)
+		} else {
+			items, err = planEC2(priced, o.TargetMRR*s.Share, o.Tolerance, cons)
+		}
+		if err != nil {
+			if s.Name != "" {
+				return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+			}
+			return nil, err
+		}
+		for i := range items {
+			items[i].Schedule = s.Name
+			if s.AlwaysOn() {
+				items[i].Hours = 0
+			}
+		}
+		plan = append(plan, items...)
+	}
+	return plan, nil
+}
+
+func (o *Orchestrator) logPlan(plan []PlanItem) {
+	l := o.logger().With("step", "plan")
+	for _, it := range plan {
+		attrs := []any{"count", it.Count, "instance", it.Name, "monthly", it.Monthly, "total", float64(it.Count) * it.Monthly}
+		if it.Schedule != "" {
+			// Horas/mês do item no seu schedule.
+			attrs = append(attrs, "schedule", it.Schedule, "hours", it.Hours)
+		}
+		l.Info("plan item", attrs...)
+	}
+	attrs := []any{"total", planTotal(plan), "upfront", planUpfront(plan), "os", OSLabel(o.OS), "purchase", o.Purchase.Label()}
+	if o.Workload != nil {
+		v, m := planCapacity(plan)
+		attrs = append(attrs, "workload", fmt.Sprintf("%+v", *o.Workload), "vcpus", v, "memory_gib", m)
+	} else {
+		attrs = append(attrs, "target", o.TargetMRR, "tolerance", tolOrDefault(o.Tolerance))
+	}
+	l.Info("plan", attrs...)
+}
+
+func tolOrDefault(tol float64) float64 {
+	if tol <= 0 {
+		return defaultTolerance
+	}
+	return tol
+}
+
+func compactPlan(in []PlanItem) []PlanItem {
+	if len(in) == 0 {
+		return in
+	}
+	m := map[string]*PlanItem{}
+	for _, it := range in {
+		if it.Count <= 0 {
+			continue
+		}
+		key := it.Name + "|" + it.Schedule
+		if ex, ok := m[key]; ok {
+			ex.Count += it.Count
+		} else {
+			cp := it
+			m[key] = &cp
+		}
+	}
+	out := make([]PlanItem, 0, len(m))
+	for _, v := range m {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Monthly != out[j].Monthly {
+			return out[i].Monthly > out[j].Monthly
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func planTotal(plan []PlanItem) float64 {
+	total := 0.0
+	for _, it := range plan {
+		total += float64(it.Count) * it.Monthly
+	}
+	return total
+}
+
+// planRecurring is the plan's monthly cost without the amortized upfront,
+// the monthly cost the calculator shows.
+func planRecurring(plan []PlanItem) float64 {
+	total := 0.0
+	for _, it := range plan {
+		total += float64(it.Count) * it.Recurring
+	}
+	return total
+}
+
+func planUpfront(plan []PlanItem) float64 {
+	total := 0.0
+	for _, it := range plan {
+		total += float64(it.Count) * it.Upfront
+	}
+	return total
+}
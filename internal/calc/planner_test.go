@@ -0,0 +1,117 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+)
+
+func testCatalog() []ec2Option {
+	return []ec2Option{
+		{Name: "m7g.medium", Hourly: 0.0408, Monthly: 0.0408 * 730},
+		{Name: "c6g.large", Hourly: 0.068, Monthly: 0.068 * 730},
+		{Name: "r7g.xlarge", Hourly: 0.2142, Monthly: 0.2142 * 730},
+		{Name: "r6g.4xlarge", Hourly: 0.8064, Monthly: 0.8064 * 730},
+		{Name: "m7g.8xlarge", Hourly: 1.3056, Monthly: 1.3056 * 730},
+	}
+}
+
+func TestPlanEC2WithinTolerance(t *testing.T) {
+	for _, target := range []float64{500, 1000, 4321.5, 25000, 120000} {
+		plan, err := planEC2(testCatalog(), target, 0.03, PlanConstraints{})
+		if err != nil {
+			t.Fatalf("target %.2f: %v", target, err)
+		}
+		got := planTotal(plan)
+		if rel := math.Abs(got-target) / target; rel > 0.03 {
+			t.Fatalf("target %.2f: total %.2f off by %.4f", target, got, rel)
+		}
+	}
+}
+
+func TestPlanEC2NoCombination(t *testing.T) {
+	opts := []ec2Option{{Name: "m7g.8xlarge", Hourly: 1.3056, Monthly: 953.088}}
+	if _, err := planEC2(opts, 1400, 0.01, PlanConstraints{}); err == nil {
+		t.Fatalf("expected error when no combination fits")
+	}
+	if _, err := planEC2(opts, 100, 0.03, PlanConstraints{}); err == nil {
+		t.Fatalf("expected error when every option exceeds the target")
+	}
+	if _, err := planEC2(nil, 100, 0.03, PlanConstraints{}); err == nil {
+		t.Fatalf("expected error for empty catalog")
+	}
+}
+
+func TestPlanEC2Constraints(t *testing.T) {
+	cons := PlanConstraints{MaxTypes: 2, MinCount: 2, MaxCount: 20, Multiple: 2}
+	for _, target := range []float64{3000, 9000, 15000} {
+		plan, err := planEC2(testCatalog(), target, 0.03, cons)
+		if err != nil {
+			t.Fatalf("target %.2f: %v", target, err)
+		}
+		if len(plan) > cons.MaxTypes {
+			t.Fatalf("target %.2f: %d types, want <= %d", target, len(plan), cons.MaxTypes)
+		}
+		for _, it := range plan {
+			if it.Count < cons.MinCount || it.Count > cons.MaxCount || it.Count%cons.Multiple != 0 {
+				t.Fatalf("target %.2f: count %d for %s violates %+v", target, it.Count, it.Name, cons)
+			}
+		}
+		if rel := math.Abs(planTotal(plan)-target) / target; rel > 0.03 {
+			t.Fatalf("target %.2f: off by %.4f", target, rel)
+		}
+	}
+}
+
+func TestPlanEC2LargeCatalogMemory(t *testing.T) {
+	// An imported offer file prices hundreds of instance types.
+	opts := make([]ec2Option, 1500)
+	for i := range opts {
+		m := 5 + float64(i)*0.37
+		opts[i] = ec2Option{Name: fmt.Sprintf("x%d.large", i), Hourly: m / 730, Monthly: m}
+	}
+	cons := PlanConstraints{MaxTypes: 3}
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	plan, err := planEC2(opts, 50000, 0.03, cons)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) > cons.MaxTypes || math.Abs(planTotal(plan)-50000)/50000 > 0.03 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 64<<20 {
+		t.Errorf("planEC2 allocated %d MiB for %d options", alloc>>20, len(opts))
+	}
+}
+
+func TestPlanConstraintsValidate(t *testing.T) {
+	bad := []PlanConstraints{
+		{MinCount: 5, MaxCount: 2},
+		{MinCount: 3, MaxCount: 3, Multiple: 2},
+		{MaxTypes: -1},
+	}
+	for _, c := range bad {
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+	if err := (PlanConstraints{MinCount: 1, MaxCount: 4, Multiple: 2}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCompactPlanMerges(t *testing.T) {
+	got := compactPlan([]PlanItem{
+		{Name: "a", Monthly: 10, Count: 1},
+		{Name: "b", Monthly: 20, Count: 2},
+		{Name: "a", Monthly: 10, Count: 3},
+		{Name: "c", Monthly: 5, Count: 0},
+	})
+	if len(got) != 2 || got[0].Name != "b" || got[1].Count != 4 {
+		t.Fatalf("unexpected plan: %#v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ---- Operating system and license variants ----
+
+// Operating system keys used in pricing.yaml ("os:") and by the map command.
+const (
+	OSLinux         = "linux"
+	OSWindows       = "windows"
+	OSRHEL          = "rhel"
+	OSSUSE          = "suse"
+	OSWindowsSQLWeb = "windows-sql-web"
+	OSWindowsSQLStd = "windows-sql-std"
+	OSWindowsSQLEnt = "windows-sql-ent"
+)
+
+// osLabels are the options of the calculator's "Operating system" selector.
+var osLabels = map[string]string{
+	OSLinux:         "Linux",
+	OSWindows:       "Windows Server",
+	OSRHEL:          "Red Hat Enterprise Linux",
+	OSSUSE:          "SUSE Linux Enterprise Server",
+	OSWindowsSQLWeb: "Windows Server with SQL Server Web",
+	OSWindowsSQLStd: "Windows Server with SQL Server Standard",
+	OSWindowsSQLEnt: "Windows Server with SQL Server Enterprise",
+}
+
+// ParseOperatingSystem normalizes user spellings ("Windows", "sql-standard",
+// "red hat", ...) to one of the OS keys. Empty means Linux.
+func ParseOperatingSystem(s string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.NewReplacer("_", "-", " ", "-").Replace(v)
+	switch v {
+	case "", "linux", "amazon-linux", "linux/unix":
+		return OSLinux, nil
+	case "windows", "windows-server", "win":
+		return OSWindows, nil
+	case "rhel", "red-hat", "redhat", "red-hat-enterprise-linux":
+		return OSRHEL, nil
+	case "suse", "sles", "suse-linux":
+		return OSSUSE, nil
+	case "windows-sql-web", "sql-web":
+		return OSWindowsSQLWeb, nil
+	case "windows-sql-std", "windows-sql-standard", "sql-std", "sql-standard":
+		return OSWindowsSQLStd, nil
+	case "windows-sql-ent", "windows-sql-enterprise", "sql-ent", "sql-enterprise":
+		return OSWindowsSQLEnt, nil
+	}
+	return "", fmt.Errorf("unknown operating system %q", s)
+}
+
+// OSLabel returns the calculator label for an OS key.
+func OSLabel(os string) string {
+	if l, ok := osLabels[os]; ok {
+		return l
+	}
+	return osLabels[OSLinux]
+}
+
+// osRunsOn reports whether the OS is offered for the instance architecture;
+// Windows and SQL Server are x86 only.
+func osRunsOn(os, name string) bool {
+	if !strings.HasPrefix(os, OSWindows) {
+		return true
+	}
+	n, ok := parseInstanceName(name)
+	return !ok || n.Arch() != ArchARM64
+}
+
+// withLicense adds an hourly per-vCPU license fee to a Linux price, for
+// months of hpm hours. License fees are not discounted by commitments, so
+// they are added as recurring.
+func withLicense(o ec2Option, perVCPUHour, hpm float64) ec2Option {
+	fee := perVCPUHour * float64(o.VCPUs)
+	o.Hourly += fee
+	o.Recurring += fee * hpm
+	o.Monthly += fee * hpm
+	return o
+}
+
+// ---- Browser: operating system in the EC2 configurator ----
+
+// ensureOperatingSystem opens the "Operating system" selector and picks the
+// option for os. Linux is the calculator default and is left untouched.
+func ensureOperatingSystem(ctx context.Context, sp *SelectorPack, os string, d time.Duration) error {
+	if os == "" || os == OSLinux {
+		return nil
+	}
+	label := OSLabel(os)
+	if err := clickElement(ctx, sp.el("os_trigger"), d); err != nil {
+		logger(ctx).Debug("operating system menu not found", "err", err)
+	}
+	_ = chromedp.Run(ctx, chromedp.Sleep(200*time.Millisecond))
+	if err := clickElement(ctx, sp.el("os_option", label), d); err != nil {
+		if !deepClickOptionByExactText(ctx, label) {
+			return fmt.Errorf("could not select operating system %q", label)
+		}
+	}
+	logger(ctx).Debug("operating system selected", "os", label)
+	return nil
+}
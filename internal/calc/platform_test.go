@@ -0,0 +1,102 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseOperatingSystem(t *testing.T) {
+	cases := map[string]string{
+		"":                       OSLinux,
+		"Linux":                  OSLinux,
+		"Windows":                OSWindows,
+		"red hat":                OSRHEL,
+		"SLES":                   OSSUSE,
+		"sql_standard":           OSWindowsSQLStd,
+		"windows-sql-enterprise": OSWindowsSQLEnt,
+	}
+	for in, want := range cases {
+		if got, err := ParseOperatingSystem(in); err != nil || got != want {
+			t.Errorf("ParseOperatingSystem(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseOperatingSystem("beos"); err == nil {
+		t.Fatal("expected error for unknown OS")
+	}
+}
+
+func TestCatalogOperatingSystems(t *testing.T) {
+	doc := `
+discounts:
+  ri-1yr-no-upfront: 0.5
+os_surcharges:
+  windows: 0.05
+regions:
+  us-east-1:
+    ec2:
+      - name: m7i.large
+        hourly: 0.1
+        vcpus: 2
+        memory_gib: 8
+      - name: m7g.large
+        hourly: 0.08
+        vcpus: 2
+        memory_gib: 8
+      - name: c7i.large
+        os: windows
+        hourly: 0.2
+        vcpus: 2
+        memory_gib: 4
+`
+	cat := parsePricingYAML([]byte(doc))
+
+	linux, err := cat.Options("us-east-1", OSLinux, PurchaseOption{}, 0)
+	if err != nil || len(linux) != 2 {
+		t.Fatalf("expected 2 Linux options, got %+v (%v)", linux, err)
+	}
+
+	win, err := cat.Options("us-east-1", OSWindows, PurchaseOption{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, o := range win {
+		if o.OS != OSWindows {
+			t.Errorf("%s: OS = %q, want windows", o.Name, o.OS)
+		}
+		got[o.Name] = o.Hourly
+	}
+	if _, ok := got["m7g.large"]; ok {
+		t.Error("Windows must not be planned on arm64")
+	}
+	if math.Abs(got["m7i.large"]-0.2) > 1e-9 || math.Abs(got["c7i.large"]-0.2) > 1e-9 {
+		t.Fatalf("unexpected Windows prices: %+v", got)
+	}
+
+	// The license fee is added on top of the discounted Linux price; explicit
+	// Windows On-Demand prices are discounted as a whole.
+	ri, _ := ParsePurchaseOption("ri-1yr-no-upfront")
+	win, err = cat.Options("us-east-1", OSWindows, ri, 0)
+	if err != nil || len(win) != 2 {
+		t.Fatalf("unexpected Windows RI prices: %+v (%v)", win, err)
+	}
+	for _, o := range win {
+		want := map[string]float64{"c7i.large": 0.1, "m7i.large": 0.05 + 2*0.05}[o.Name]
+		if math.Abs(o.Hourly-want) > 1e-9 {
+			t.Errorf("%s RI hourly = %v, want %v", o.Name, o.Hourly, want)
+		}
+	}
+
+	if _, err := cat.Options("us-east-1", OSRHEL, PurchaseOption{}, 0); err == nil {
+		t.Fatal("expected error without RHEL prices or surcharge")
+	}
+
+	// The license fee covers the same month as the Linux price.
+	cat = parsePricingYAML([]byte("hours_per_month: 720\n" + doc))
+	win, _ = cat.Options("us-east-1", OSWindows, PurchaseOption{}, 0)
+	for _, o := range win {
+		if o.Name == "m7i.large" && math.Abs(o.Recurring-0.2*720) > 1e-9 {
+			t.Errorf("m7i.large Windows recurring = %v, want %v", o.Recurring, 0.2*720)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ---- Purchase models (On-Demand, Spot, Reserved Instances, Savings Plans) ----
+
+// Purchase kinds.
+const (
+	PurchaseOnDemand      = "on-demand"
+	PurchaseSpot          = "spot"
+	PurchaseReserved      = "ri"
+	PurchaseComputeSP     = "compute-sp"
+	PurchaseEC2InstanceSP = "ec2-sp"
+)
+
+// Payment options of commitment purchases.
+const (
+	PaymentNoUpfront      = "no-upfront"
+	PaymentPartialUpfront = "partial-upfront"
+	PaymentAllUpfront     = "all-upfront"
+)
+
+// PurchaseOption is how instances are paid for. Term (years) and Payment only
+// apply to Reserved Instances and Savings Plans. The zero value is On-Demand.
+type PurchaseOption struct {
+	Kind    string
+	Term    int
+	Payment string
+}
+
+// ParsePurchaseOption accepts "on-demand", "spot" and commitment keys such as
+// "ri-1yr-no-upfront", "compute-sp-3yr-all-upfront" or "ec2-sp-1yr-partial-upfront".
+// The payment option defaults to no upfront.
+func ParsePurchaseOption(s string) (PurchaseOption, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.ReplaceAll(v, "_", "-")
+	switch v {
+	case "", "on-demand", "ondemand", "od":
+		return PurchaseOption{Kind: PurchaseOnDemand}, nil
+	case "spot":
+		return PurchaseOption{Kind: PurchaseSpot}, nil
+	}
+	var p PurchaseOption
+	for _, k := range []struct{ prefix, kind string }{
+		{"ri-", PurchaseReserved},
+		{"compute-sp-", PurchaseComputeSP},
+		{"csp-", PurchaseComputeSP},
+		{"ec2-sp-", PurchaseEC2InstanceSP},
+		{"ec2isp-", PurchaseEC2InstanceSP},
+	} {
+		if strings.HasPrefix(v, k.prefix) {
+			p.Kind = k.kind
+			v = strings.TrimPrefix(v, k.prefix)
+			break
+		}
+	}
+	if p.Kind == "" {
+		return p, fmt.Errorf("unknown purchase option %q", s)
+	}
+	term, payment, _ := strings.Cut(v, "-")
+	switch term {
+	case "1yr", "1y", "1":
+		p.Term = 1
+	case "3yr", "3y", "3":
+		p.Term = 3
+	default:
+		return p, fmt.Errorf("purchase option %q needs a 1yr or 3yr term", s)
+	}
+	switch payment {
+	case "", "no-upfront", "no":
+		p.Payment = PaymentNoUpfront
+	case "partial-upfront", "partial":
+		p.Payment = PaymentPartialUpfront
+	case "all-upfront", "all":
+		p.Payment = PaymentAllUpfront
+	default:
+		return p, fmt.Errorf("purchase option %q has unknown payment %q", s, payment)
+	}
+	return p, nil
+}
+
+// Key is the canonical catalog key, e.g. "ri-1yr-partial-upfront".
+func (p PurchaseOption) Key() string {
+	switch p.Kind {
+	case "", PurchaseOnDemand:
+		return PurchaseOnDemand
+	case PurchaseSpot:
+		return PurchaseSpot
+	}
+	return fmt.Sprintf("%s-%dyr-%s", p.Kind, p.Term, p.Payment)
+}
+
+// Label is the human-readable name used in the output.
+func (p PurchaseOption) Label() string {
+	var kind string
+	switch p.Kind {
+	case "", PurchaseOnDemand:
+		return "On-Demand"
+	case PurchaseSpot:
+		return "Spot"
+	case PurchaseReserved:
+		kind = "Standard Reserved Instances"
+	case PurchaseComputeSP:
+		kind = "Compute Savings Plans"
+	case PurchaseEC2InstanceSP:
+		kind = "EC2 Instance Savings Plans"
+	}
+	return fmt.Sprintf("%s %dyr %s", kind, p.Term, paymentLabel(p.Payment))
+}
+
+// IsCommitment reports whether the option is a Reserved Instance or Savings Plan.
+func (p PurchaseOption) IsCommitment() bool {
+	return p.Term > 0
+}
+
+// termMonths is the commitment length in months (0 for On-Demand and Spot).
+func (p PurchaseOption) termMonths() int {
+	return 12 * p.Term
+}
+
+func paymentLabel(payment string) string {
+	switch payment {
+	case PaymentPartialUpfront:
+		return "Partial upfront"
+	case PaymentAllUpfront:
+		return "All upfront"
+	default:
+		return "No upfront"
+	}
+}
+
+// priceFor builds an option from a recurring hourly rate and a one-time
+// upfront fee (per instance, for the whole term), for months of hpm hours.
+// Monthly is the effective monthly cost the planner targets: recurring plus
+// the upfront amortized over the term.
+func priceFor(p PurchaseOption, name string, hourly, upfront, hpm float64) ec2Option {
+	o := ec2Option{Name: name, Hourly: hourly, Recurring: hourly * hpm, Upfront: upfront}
+	o.Monthly = o.Recurring
+	if m := p.termMonths(); m > 0 {
+		o.Monthly += upfront / float64(m)
+	}
+	return o
+}
+
+// derivePrice estimates a purchase option's price from the On-Demand price
+// and an average discount (fraction of the On-Demand cost saved). Partial
+// upfront pays half of the term cost upfront, all upfront pays all of it,
+// for months of hpm hours.
+func derivePrice(p PurchaseOption, od ec2Option, discount, hpm float64) ec2Option {
+	eff := od.Hourly * (1 - discount)
+	hourly, upfront := eff, 0.0
+	switch p.Payment {
+	case PaymentPartialUpfront:
+		hourly = eff / 2
+		upfront = eff / 2 * hpm * float64(p.termMonths())
+	case PaymentAllUpfront:
+		hourly = 0
+		upfront = eff * hpm * float64(p.termMonths())
+	}
+	o := priceFor(p, od.Name, hourly, upfront, hpm)
+	o.VCPUs, o.MemoryGiB, o.OS = od.VCPUs, od.MemoryGiB, od.OS
+	return o
+}
+
+// ---- Browser: pricing option in the EC2 configurator ----
+
+// ensurePurchaseOption selects the pricing model, term and payment option
+// matching p. Like the other configurator helpers it is best effort: missing
+// controls are logged, not fatal.
+func ensurePurchaseOption(ctx context.Context, sp *SelectorPack, p PurchaseOption, d time.Duration) error {
+	var steps []string
+	switch p.Kind {
+	case "", PurchaseOnDemand:
+		steps = []string{"On-Demand"}
+	case PurchaseSpot:
+		steps = []string{"Spot"}
+	case PurchaseReserved:
+		steps = []string{"Standard Reserved Instances", "Reserved"}
+	case PurchaseComputeSP:
+		steps = []string{"Compute Savings Plans"}
+	case PurchaseEC2InstanceSP:
+		steps = []string{"EC2 Instance Savings Plans"}
+	}
+	if !clickFirstText(ctx, sp, steps, d) {
+		logger(ctx).Warn("could not select purchase option", "purchase", p.Label())
+	}
+	if !p.IsCommitment() {
+		return nil
+	}
+	_ = chromedp.Run(ctx, chromedp.Sleep(200*time.Millisecond))
+	term := []string{fmt.Sprintf("%d year", p.Term), fmt.Sprintf("%dyr", p.Term)}
+	if p.Term > 1 {
+		term = append([]string{fmt.Sprintf("%d years", p.Term)}, term...)
+	}
+	if !clickFirstText(ctx, sp, term, d) {
+		logger(ctx).Warn("could not select term", "years", p.Term)
+	}
+	if !clickFirstText(ctx, sp, []string{paymentLabel(p.Payment)}, d) {
+		logger(ctx).Warn("could not select payment", "payment", paymentLabel(p.Payment))
+	}
+	return nil
+}
+
+// clickFirstText clicks the first radio/label whose text contains one of texts.
+func clickFirstText(ctx context.Context, sp *SelectorPack, texts []string, d time.Duration) bool {
+	for _, t := range texts {
+		if err := clickElement(ctx, sp.el("purchase_option", t), d); err == nil {
+			return true
+		}
+		if deepClickButtonByText(ctx, t) {
+			return true
+		}
+	}
+	return false
+}
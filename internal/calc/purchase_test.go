@@ -0,0 +1,107 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePurchaseOption(t *testing.T) {
+	cases := map[string]string{
+		"":                           "on-demand",
+		"On-Demand":                  "on-demand",
+		"spot":                       "spot",
+		"ri-1yr-no-upfront":          "ri-1yr-no-upfront",
+		"RI_3yr_all_upfront":         "ri-3yr-all-upfront",
+		"csp-3yr":                    "compute-sp-3yr-no-upfront",
+		"ec2-sp-1yr-partial-upfront": "ec2-sp-1yr-partial-upfront",
+	}
+	for in, want := range cases {
+		p, err := ParsePurchaseOption(in)
+		if err != nil || p.Key() != want {
+			t.Errorf("ParsePurchaseOption(%q) = %q, %v; want %q", in, p.Key(), err, want)
+		}
+	}
+	for _, bad := range []string{"ri", "ri-2yr", "ri-1yr-sometimes", "lease"} {
+		if _, err := ParsePurchaseOption(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCatalogPurchaseOptions(t *testing.T) {
+	doc := `
+discounts:
+  ri-1yr-all-upfront: 0.4
+regions:
+  us-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.1
+      - name: c7g.large
+        hourly: 0.08
+      - name: m7g.large
+        purchase: ri-1yr-partial-upfront
+        hourly: 0.03
+        upfront: 262.8
+`
+	cat := parsePricingYAML([]byte(doc))
+
+	partial, _ := ParsePurchaseOption("ri-1yr-partial-upfront")
+	opts, err := cat.Options("us-east-1", "", partial, 0)
+	if err != nil || len(opts) != 1 {
+		t.Fatalf("expected the explicit partial upfront price, got %+v (%v)", opts, err)
+	}
+	if o := opts[0]; math.Abs(o.Recurring-21.9) > 1e-9 || math.Abs(o.Monthly-(21.9+21.9)) > 1e-9 {
+		t.Fatalf("unexpected split: %+v", o)
+	}
+
+	all, _ := ParsePurchaseOption("ri-1yr-all-upfront")
+	opts, err = cat.Options("us-east-1", "", all, 0)
+	if err != nil || len(opts) != 2 {
+		t.Fatalf("expected derived prices for both types, got %+v (%v)", opts, err)
+	}
+	for _, o := range opts {
+		if o.Recurring != 0 || o.Upfront <= 0 {
+			t.Fatalf("all upfront should have no recurring charge: %+v", o)
+		}
+	}
+	if want := 0.1 * 0.6 * hoursPerMonth; math.Abs(opts[1].Monthly-want) > 1e-9 {
+		t.Fatalf("effective monthly = %.4f, want %.4f", opts[1].Monthly, want)
+	}
+
+	if _, err := cat.Options("us-east-1", "", PurchaseOption{Kind: PurchaseSpot}, 0); err == nil {
+		t.Fatalf("expected error for purchase option without prices or discount")
+	}
+}
+
+func TestDerivedPricesUseCatalogMonth(t *testing.T) {
+	cat := parsePricingYAML([]byte(`
+hours_per_month: 720
+discounts:
+  ri-1yr-partial-upfront: 0.4
+regions:
+  us-east-1:
+    ec2:
+      - name: m7g.large
+        hourly: 0.1
+`))
+	partial, _ := ParsePurchaseOption("ri-1yr-partial-upfront")
+	opts, err := cat.Options("us-east-1", "", partial, 0)
+	if err != nil || len(opts) != 1 {
+		t.Fatalf("expected a derived price, got %+v (%v)", opts, err)
+	}
+	// Half of 12 months of 720 hours at the discounted rate is paid upfront,
+	// and the amortized upfront matches the recurring half.
+	if o := opts[0]; math.Abs(o.Upfront-0.03*720*12) > 1e-9 || math.Abs(o.Recurring-0.03*720) > 1e-9 {
+		t.Fatalf("derived price not based on 720 hours: %+v", o)
+	}
+	if o := reprice(opts, partial, 720, 720)[0]; math.Abs(o.Monthly-0.06*720) > 1e-9 {
+		t.Fatalf("monthly = %.4f, want %.4f", o.Monthly, 0.06*720)
+	}
+
+	// The run's month overrides the catalog's.
+	opts, _ = cat.Options("us-east-1", "", partial, 744)
+	if o := opts[0]; math.Abs(o.Upfront-0.03*744*12) > 1e-9 {
+		t.Fatalf("derived price not based on the run's 744 hours: %+v", o)
+	}
+}
@@ -0,0 +1,75 @@
+package calc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ---- ARR ramp (consumption commitment per period) ----
+
+// RampPeriod is one period of a MAP consumption ramp, e.g. year 1 at 40% of
+// the target ARR.
+type RampPeriod struct {
+	Name string
+	ARR  float64
+}
+
+// TargetMRR is the monthly target of the period.
+func (p RampPeriod) TargetMRR() float64 { return p.ARR / 12 }
+
+// ParseRamp reads "name:arr" items separated by commas, e.g.
+// "Y1:480000,Y2:960000,Y3:1200000". A value ending in % is a share of arr
+// ("Y1:40%,Y2:80%,Y3:100%") and names may be omitted ("40%,80%,100%" names
+// the periods Y1, Y2, ...).
+func ParseRamp(s string, arr float64) ([]RampPeriod, error) {
+	var out []RampPeriod
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p := RampPeriod{Name: fmt.Sprintf("Y%d", len(out)+1)}
+		value := item
+		if name, v, ok := strings.Cut(item, ":"); ok {
+			p.Name, value = strings.TrimSpace(name), strings.TrimSpace(v)
+			if p.Name == "" {
+				return nil, fmt.Errorf("invalid ramp period %q (use name:arr, e.g. Y1:480000 or Y1:40%%)", item)
+			}
+		}
+		if pct, ok := strings.CutSuffix(value, "%"); ok {
+			if arr <= 0 {
+				return nil, fmt.Errorf("ramp period %s is a percentage; arr is required", p.Name)
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid percentage %q in ramp period %s", value, p.Name)
+			}
+			p.ARR = arr * v / 100
+		} else {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid ARR %q in ramp period %s (use a positive amount or a percentage)", value, p.Name)
+			}
+			p.ARR = v
+		}
+		for _, prev := range out {
+			if prev.Name == p.Name {
+				return nil, fmt.Errorf("duplicate ramp period %q", p.Name)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// RampPeak returns the period with the highest ARR.
+func RampPeak(periods []RampPeriod) RampPeriod {
+	var peak RampPeriod
+	for _, p := range periods {
+		if p.ARR > peak.ARR {
+			peak = p
+		}
+	}
+	return peak
+}
@@ -0,0 +1,30 @@
+package calc
+
+import "testing"
+
+func TestParseRamp(t *testing.T) {
+	got, err := ParseRamp("Y1:480000, Y2:960000, Y3:1200000", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "Y1" || got[2].ARR != 1200000 || got[1].TargetMRR() != 80000 {
+		t.Fatalf("unexpected ramp: %+v", got)
+	}
+	if peak := RampPeak(got); peak.Name != "Y3" {
+		t.Errorf("peak = %+v", peak)
+	}
+
+	got, err = ParseRamp("40%,80%,100%", 1200000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "Y1" || got[0].ARR != 480000 || got[2].Name != "Y3" || got[2].ARR != 1200000 {
+		t.Fatalf("unexpected percentage ramp: %+v", got)
+	}
+
+	for _, bad := range []string{"Y1:40%", "Y1:abc", "Y1:-5", ":100", "Y1:1,Y1:2"} {
+		if _, err := ParseRamp(bad, 0); err == nil {
+			t.Errorf("ParseRamp(%q): expected error", bad)
+		}
+	}
+}
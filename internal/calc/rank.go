@@ -0,0 +1,321 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ---- Ranked alternative plans ----
+
+// DefaultAlternatives is the number of plans Plans returns when asked for 0.
+const DefaultAlternatives = 3
+
+// Weights of the ranking criteria; they add up to 1.
+const (
+	weightTarget    = 0.45
+	weightItems     = 0.15
+	weightFamilies  = 0.1
+	weightGraviton  = 0.15
+	weightInstances = 0.15
+)
+
+// maxVariantCount caps the count of every instance type in the variants
+// explored besides the base plan, unless the run sets MaxCount: a variant
+// restricted to one type otherwise fills the target with hundreds of tiny
+// instances.
+const maxVariantCount = 100
+
+// RankedPlan is one candidate plan with its score (0-100) and a short
+// explanation of the trade-offs behind its rank.
+type RankedPlan struct {
+	Rank          int
+	Items         []PlanItem
+	Total         float64
+	Upfront       float64
+	RelativeError float64
+	Families      []string
+	GravitonShare float64
+	// Instances is the number of instances of the plan, all types together.
+	Instances   int
+	Score       float64
+	Explanation string
+}
+
+// Label is a one-line summary used in selectors and logs.
+func (p RankedPlan) Label() string {
+	parts := make([]string, 0, len(p.Items))
+	for _, it := range p.Items {
+		parts = append(parts, fmt.Sprintf("%d x %s", it.Count, it.Name))
+	}
+	return fmt.Sprintf("#%d (score %.0f) %s — $%.2f/mo", p.Rank, p.Score, strings.Join(parts, " + "), p.Total)
+}
+
+// Plans runs the planner without a browser and returns up to n candidate
+// plans, best first.
+func (o *Orchestrator) Plans(n int) ([]RankedPlan, error) {
+	return o.rankedPlans(n)
+}
+
+// rankedPlans explores variants of the run (fewer instance types, one
+// architecture only, without each family of the base plan), scores every
+// distinct plan on target fit, line items, instance families and Graviton
+// share, and returns the best n.
+func (o *Orchestrator) rankedPlans(n int) ([]RankedPlan, error) {
+	if n <= 0 {
+		n = DefaultAlternatives
+	}
+	opts, hpm, err := o.options()
+	if err != nil {
+		return nil, err
+	}
+	base, err := o.solve(opts, hpm, o.Constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var cands [][]PlanItem
+	add := func(p []PlanItem) {
+		if sig := planSignature(p); len(p) > 0 && !seen[sig] {
+			seen[sig] = true
+			cands = append(cands, p)
+		}
+	}
+	add(base)
+	try := func(opts []ec2Option, cons PlanConstraints) {
+		if len(opts) == 0 {
+			return
+		}
+		if p, err := o.solve(opts, hpm, cons); err == nil {
+			add(p)
+		}
+	}
+
+	variant := o.Constraints
+	if variant.MaxCount == 0 {
+		variant.MaxCount = maxVariantCount
+	}
+	maxTypes := 3
+	if o.Constraints.MaxTypes > 0 {
+		maxTypes = o.Constraints.MaxTypes - 1
+	}
+	for k := 1; k <= maxTypes; k++ {
+		cons := variant
+		cons.MaxTypes = k
+		try(opts, cons)
+	}
+	for _, arch := range []string{ArchARM64, ArchX86} {
+		try(CatalogFilter{Arch: arch}.Apply(opts), variant)
+	}
+	for i, fam := range planFamilies(base) {
+		if i == 3 {
+			break
+		}
+		try(CatalogFilter{ExcludeFamilies: []string{fam}}.Apply(opts), variant)
+	}
+
+	ranked := o.scorePlans(cands)
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	for _, p := range ranked {
+		o.logger().Info("alternative plan", "rank", p.Rank, "score", p.Score, "total", p.Total, "plan", p.Label(), "why", p.Explanation)
+	}
+	return ranked, nil
+}
+
+// scorePlans scores and sorts the candidates and writes their explanations.
+func (o *Orchestrator) scorePlans(cands [][]PlanItem) []RankedPlan {
+	cheapest := math.Inf(1)
+	fewest := math.MaxInt
+	for _, p := range cands {
+		cheapest = math.Min(cheapest, planTotal(p))
+		fewest = min(fewest, planCount(p))
+	}
+	tol := tolOrDefault(o.Tolerance)
+
+	ranked := make([]RankedPlan, 0, len(cands))
+	for _, items := range cands {
+		r := RankedPlan{
+			Items:         items,
+			Total:         planTotal(items),
+			Upfront:       planUpfront(items),
+			Families:      planFamilies(items),
+			GravitonShare: gravitonShare(items),
+			Instances:     planCount(items),
+		}
+		if o.TargetMRR > 0 && o.Workload == nil {
+			r.RelativeError = math.Abs(r.Total-o.TargetMRR) / o.TargetMRR
+		}
+		ranked = append(ranked, r)
+	}
+	targetFit := func(r RankedPlan) float64 {
+		if o.Workload != nil {
+			// Every workload plan covers the target; the cheaper the better.
+			if r.Total <= 0 {
+				return 0
+			}
+			return cheapest / r.Total
+		}
+		return math.Max(0, 1-r.RelativeError/tol)
+	}
+	for i := range ranked {
+		r := &ranked[i]
+		r.Score = 100 * (weightTarget*targetFit(*r) +
+			weightItems/float64(len(r.Items)) +
+			weightFamilies/float64(max(1, len(r.Families))) +
+			weightGraviton*r.GravitonShare +
+			weightInstances*float64(fewest)/float64(max(1, r.Instances)))
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Total < ranked[j].Total
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	for i := range ranked {
+		ranked[i].Explanation = o.explain(ranked, i, targetFit)
+	}
+	return ranked
+}
+
+// explain describes a plan and how it compares to the other candidates.
+func (o *Orchestrator) explain(ranked []RankedPlan, i int, targetFit func(RankedPlan) float64) string {
+	r := ranked[i]
+	var facts []string
+	if o.Workload != nil {
+		// targetFit is cheapest/Total in workload mode.
+		if over := 1/targetFit(r) - 1; over > 0.0005 {
+			facts = append(facts, fmt.Sprintf("%.1f%% above the cheapest candidate", over*100))
+		} else {
+			facts = append(facts, "cheapest candidate")
+		}
+	} else {
+		facts = append(facts, fmt.Sprintf("%.2f%% from target", r.RelativeError*100))
+	}
+	facts = append(facts, plural(len(r.Items), "line item", "line items"))
+	facts = append(facts, plural(r.Instances, "instance", "instances"))
+	facts = append(facts, fmt.Sprintf("%s (%s)", plural(len(r.Families), "family", "families"), strings.Join(r.Families, ", ")))
+	facts = append(facts, fmt.Sprintf("%.0f%% Graviton", r.GravitonShare*100))
+
+	type criterion struct {
+		best   string
+		behind string
+		value  func(RankedPlan) float64 // higher is better
+	}
+	criteria := []criterion{
+		{"best target fit", "target fit", targetFit},
+		{"fewest line items", "line items", func(p RankedPlan) float64 { return -float64(len(p.Items)) }},
+		{"fewest instances", "instance count", func(p RankedPlan) float64 { return -float64(p.Instances) }},
+		{"fewest families", "families", func(p RankedPlan) float64 { return -float64(len(p.Families)) }},
+		{"most Graviton", "Graviton share", func(p RankedPlan) float64 { return p.GravitonShare }},
+	}
+	var best, behind []string
+	for _, c := range criteria {
+		top := true
+		for _, other := range ranked {
+			if c.value(other) > c.value(r)+1e-9 {
+				top = false
+				break
+			}
+		}
+		if top && len(ranked) > 1 {
+			best = append(best, c.best)
+		}
+		if i > 0 && c.value(ranked[0]) > c.value(r)+1e-9 {
+			behind = append(behind, c.behind)
+		}
+	}
+	out := strings.Join(facts, ", ")
+	if len(best) > 0 {
+		out += "; " + strings.Join(best, ", ")
+	}
+	if len(behind) > 0 {
+		out += "; behind #1 on " + strings.Join(behind, ", ")
+	}
+	return out
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// planCount is the number of instances of the plan.
+func planCount(plan []PlanItem) int {
+	n := 0
+	for _, it := range plan {
+		n += it.Count
+	}
+	return n
+}
+
+// planSignature identifies a plan by its instance types and counts.
+func planSignature(plan []PlanItem) string {
+	parts := make([]string, 0, len(plan))
+	for _, it := range plan {
+		parts = append(parts, fmt.Sprintf("%s:%s:%d", it.Schedule, it.Name, it.Count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// planFamilies lists the distinct instance families of a plan, by cost.
+func planFamilies(plan []PlanItem) []string {
+	var out []string
+	for _, it := range plan {
+		fam := it.Name
+		if n, ok := parseInstanceName(it.Name); ok {
+			fam = n.Family
+		}
+		if !containsString(out, fam) {
+			out = append(out, fam)
+		}
+	}
+	return out
+}
+
+// ArchMixed is the PlanArch of a plan with both arm64 and x86_64 instances.
+const ArchMixed = "mixed"
+
+// PlanArch returns the architecture of the plan's instances: ArchARM64,
+// ArchX86 or ArchMixed, or "" when no item names an instance type.
+func PlanArch(plan []PlanItem) string {
+	arch := ""
+	for _, it := range plan {
+		n, ok := parseInstanceName(it.Name)
+		if !ok {
+			continue
+		}
+		switch a := n.Arch(); {
+		case arch == "":
+			arch = a
+		case arch != a:
+			return ArchMixed
+		}
+	}
+	return arch
+}
+
+// gravitonShare is the fraction of the plan's monthly cost on arm64.
+func gravitonShare(plan []PlanItem) float64 {
+	total, arm := 0.0, 0.0
+	for _, it := range plan {
+		c := float64(it.Count) * it.Monthly
+		total += c
+		if n, ok := parseInstanceName(it.Name); ok && n.Arch() == ArchARM64 {
+			arm += c
+		}
+	}
+	if total <= 0 {
+		return 0
+	}
+	return arm / total
+}
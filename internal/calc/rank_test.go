@@ -0,0 +1,132 @@
+package calc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScorePlans(t *testing.T) {
+	o := Orchestrator{TargetMRR: 1000, Tolerance: 0.05}
+	cands := [][]PlanItem{
+		{{Name: "m7i.large", Count: 10, Monthly: 100}},
+		{{Name: "m7g.large", Count: 8, Monthly: 100}, {Name: "c7g.large", Count: 2, Monthly: 99}},
+		{{Name: "m7g.large", Count: 10, Monthly: 100}},
+	}
+	ranked := o.scorePlans(cands)
+	if len(ranked) != 3 {
+		t.Fatalf("expected 3 plans, got %d", len(ranked))
+	}
+	// Exact fit, one line item and all Graviton beats the rest.
+	if ranked[0].Items[0].Name != "m7g.large" || len(ranked[0].Items) != 1 || ranked[0].Rank != 1 {
+		t.Fatalf("unexpected #1: %+v", ranked[0])
+	}
+	if ranked[0].Score <= ranked[1].Score || ranked[1].Score <= ranked[2].Score {
+		t.Fatalf("plans not sorted by score: %+v", ranked)
+	}
+	if !strings.Contains(ranked[0].Explanation, "most Graviton") {
+		t.Errorf("#1 explanation: %s", ranked[0].Explanation)
+	}
+	for _, r := range ranked[1:] {
+		if !strings.Contains(r.Explanation, "behind #1 on") {
+			t.Errorf("#%d explanation lacks comparison: %s", r.Rank, r.Explanation)
+		}
+	}
+}
+
+func TestPlanArch(t *testing.T) {
+	cases := []struct {
+		plan []PlanItem
+		want string
+	}{
+		{nil, ""},
+		{[]PlanItem{{Name: "m7g.large"}, {Name: "a1.xlarge"}}, ArchARM64},
+		{[]PlanItem{{Name: "m7i.large"}, {Name: "c6a.xlarge"}}, ArchX86},
+		{[]PlanItem{{Name: "m7g.large"}, {Name: "m7i.large"}}, ArchMixed},
+	}
+	for _, c := range cases {
+		if got := PlanArch(c.plan); got != c.want {
+			t.Errorf("PlanArch(%v) = %q, want %q", c.plan, got, c.want)
+		}
+	}
+}
+
+func TestRankedPlans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	doc := `
+ec2:
+  - {name: m7g.medium, hourly: 0.0408}
+  - {name: c6g.large, hourly: 0.068}
+  - {name: m7i.large, hourly: 0.1008}
+  - {name: r7g.xlarge, hourly: 0.2142}
+  - {name: r6g.4xlarge, hourly: 0.8064}
+  - {name: m7g.8xlarge, hourly: 1.3056}
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EC2_PRICING_YAML", path)
+
+	o := Orchestrator{RegionCode: "us-east-1", TargetMRR: 4321.5}
+	ranked, err := o.rankedPlans(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) < 2 || len(ranked) > 4 {
+		t.Fatalf("expected 2-4 alternatives, got %d", len(ranked))
+	}
+	seen := map[string]bool{}
+	for _, r := range ranked {
+		if r.RelativeError > defaultTolerance {
+			t.Errorf("#%d misses the tolerance: %+v", r.Rank, r)
+		}
+		sig := planSignature(r.Items)
+		if seen[sig] {
+			t.Errorf("duplicate alternative %s", sig)
+		}
+		seen[sig] = true
+	}
+}
+
+func TestRankedPlansKeepSaneCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	doc := `
+ec2:
+  - {name: t3.micro, hourly: 0.0104}
+  - {name: t4g.micro, hourly: 0.0084}
+  - {name: m7g.8xlarge, hourly: 1.3056}
+  - {name: m6g.8xlarge, hourly: 1.232}
+  - {name: c7g.8xlarge, hourly: 1.1562}
+  - {name: r6g.4xlarge, hourly: 0.8064}
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EC2_PRICING_YAML", path)
+
+	o := Orchestrator{RegionCode: "us-east-1", TargetMRR: 10000}
+	ranked, err := o.rankedPlans(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range ranked {
+		for _, it := range r.Items {
+			if it.Count > maxVariantCount {
+				t.Errorf("#%d has %d x %s", r.Rank, it.Count, it.Name)
+			}
+		}
+		if r.Instances != planCount(r.Items) || r.Instances > 2*maxVariantCount {
+			t.Errorf("#%d: %d instances", r.Rank, r.Instances)
+		}
+	}
+
+	// At the same fit, many small instances rank below a few large ones.
+	scored := o.scorePlans([][]PlanItem{
+		{{Name: "t4g.micro", Count: 1640, Monthly: 6.1}},
+		{{Name: "t4g.8xlarge", Count: 10, Monthly: 1000}},
+	})
+	if scored[0].Instances != 10 || !strings.Contains(scored[1].Explanation, "instance count") {
+		t.Fatalf("unexpected ranking %+v", scored)
+	}
+}
@@ -0,0 +1,153 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ---- Retry policies: deadlines and backoff of the browser flow ----
+
+// Steps of the browser flow, the keys of DefaultRetryPolicies and
+// Orchestrator.Retry.
+const (
+	// StepRun is the whole browser flow, tried again in a fresh browser
+	// when it fails transiently.
+	StepRun       = "run"
+	StepOpen      = "open"
+	StepConfigure = "configure"
+	StepSave      = "save"
+	StepSummary   = "summary"
+	// StepReadSummary reads the calculator's totals back from the summary.
+	StepReadSummary = "read_summary"
+	StepRename      = "rename"
+	StepShare       = "share"
+	// StepSaveClick is one click on "Save and add service", repeated until
+	// the service finder is back.
+	StepSaveClick = "save_click"
+	// StepShareLink is one read of the public link after sharing.
+	StepShareLink = "share_link"
+)
+
+// RetryPolicy is how a step is tried: up to Attempts times, each attempt
+// within Timeout, waiting Backoff before the second attempt and Multiplier
+// times longer before each next one, at most MaxBackoff. Every wait is
+// jittered by ±Jitter (a fraction of it).
+type RetryPolicy struct {
+	// Attempts is the number of tries; zero means 1 + Orchestrator.MaxRetries.
+	Attempts int
+	// Timeout bounds each attempt; zero leaves only the run deadline.
+	Timeout    time.Duration
+	Backoff    time.Duration
+	Multiplier float64
+	MaxBackoff time.Duration
+	Jitter     float64
+}
+
+// DefaultRetryPolicies is the retry policy of each step of the browser flow.
+// Saving is tried once: a save that fails late may already have added the
+// service, so only its clicks are repeated, while the service finder is not
+// back.
+var DefaultRetryPolicies = map[string]RetryPolicy{
+	StepRun:         {Backoff: 5 * time.Second, Multiplier: 2, MaxBackoff: 30 * time.Second, Jitter: 0.2},
+	StepOpen:        {Attempts: 1, Timeout: 90 * time.Second},
+	StepConfigure:   {Timeout: time.Minute, Backoff: time.Second, Multiplier: 2, MaxBackoff: 8 * time.Second, Jitter: 0.2},
+	StepSave:        {Attempts: 1, Timeout: 75 * time.Second},
+	StepSummary:     {Attempts: 2, Timeout: 30 * time.Second, Backoff: time.Second, Jitter: 0.2},
+	StepReadSummary: {Attempts: 2, Timeout: 20 * time.Second, Backoff: 2 * time.Second, Jitter: 0.2},
+	StepRename:      {Attempts: 1, Timeout: 20 * time.Second},
+	StepShare:       {Attempts: 1, Timeout: 2 * time.Minute},
+	StepSaveClick:   {Attempts: 3, Timeout: 20 * time.Second},
+	StepShareLink:   {Attempts: 4, Backoff: time.Second, Multiplier: 3, MaxBackoff: 5 * time.Second, Jitter: 0.2},
+}
+
+// retryPolicies returns DefaultRetryPolicies with the overrides applied and
+// every Attempts resolved.
+func retryPolicies(overrides map[string]RetryPolicy, maxRetries int) map[string]RetryPolicy {
+	out := make(map[string]RetryPolicy, len(DefaultRetryPolicies))
+	for step, p := range DefaultRetryPolicies {
+		if o, ok := overrides[step]; ok {
+			p = o
+		}
+		if p.Attempts <= 0 {
+			p.Attempts = 1 + max(maxRetries, 0)
+		}
+		out[step] = p
+	}
+	return out
+}
+
+// delay is the wait before attempt n (1 is the second attempt), with r in
+// [0,1) picking the jitter.
+func (p RetryPolicy) delay(n int, r float64) time.Duration {
+	if p.Backoff <= 0 {
+		return 0
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	d := float64(p.Backoff) * math.Pow(mult, float64(n-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*r-1)
+	}
+	return time.Duration(d)
+}
+
+// StepError is a step of the browser flow that failed after all its
+// attempts. Its message is the last attempt's.
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string { return e.Err.Error() }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// do runs fn under the policy until it succeeds, an error is not retryable
+// (nil retries every error), the attempts run out or ctx is done.
+func (p RetryPolicy) do(ctx context.Context, step string, retryable func(error) bool, fn func(ctx context.Context) error) error {
+	attempts := max(p.Attempts, 1)
+	var err error
+	for n := 0; n < attempts; n++ {
+		if n > 0 {
+			d := p.delay(n, rand.Float64())
+			logger(ctx).Warn("retrying", "step", step, "attempt", n+1, "of", attempts, "backoff", d.Round(time.Millisecond), "err", err)
+			select {
+			case <-ctx.Done():
+				return &StepError{Step: step, Attempts: n, Err: err}
+			case <-time.After(d):
+			}
+		}
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if p.Timeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, p.Timeout)
+		}
+		err = fn(actx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || (retryable != nil && !retryable(err)) {
+			return &StepError{Step: step, Attempts: n + 1, Err: err}
+		}
+	}
+	return &StepError{Step: step, Attempts: attempts, Err: err}
+}
+
+// transient reports whether a failed run may succeed in a fresh browser: the
+// browser or the calculator could not be opened, or a step ran out of time.
+// A save that timed out is not, as it may have added the service.
+func transient(err error) bool {
+	var se *StepError
+	if !errors.As(err, &se) || se.Step == StepSave {
+		return false
+	}
+	return se.Step == StepOpen || errors.Is(se.Err, context.DeadlineExceeded)
+}
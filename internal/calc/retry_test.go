@@ -0,0 +1,143 @@
+package calc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quickRetry is DefaultRetryPolicies without waits between attempts.
+func quickRetry() map[string]RetryPolicy {
+	out := map[string]RetryPolicy{}
+	for step, p := range DefaultRetryPolicies {
+		p.Backoff = 0
+		out[step] = p
+	}
+	return out
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{Backoff: time.Second, Multiplier: 3, MaxBackoff: 5 * time.Second}
+	var got []time.Duration
+	for n := 1; n <= 4; n++ {
+		got = append(got, p.delay(n, 0.5))
+	}
+	want := []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("delays = %v, want %v", got, want)
+		}
+	}
+
+	p.Jitter = 0.2
+	if lo, hi := p.delay(1, 0), p.delay(1, 0.999999); lo != 800*time.Millisecond || hi < 1199*time.Millisecond || hi > 1200*time.Millisecond {
+		t.Errorf("jittered delay in [%s, %s], want [800ms, 1.2s]", lo, hi)
+	}
+	if d := (RetryPolicy{}).delay(3, 0.5); d != 0 {
+		t.Errorf("no backoff waited %s", d)
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := RetryPolicy{Attempts: 3, Timeout: 20 * time.Millisecond}
+
+	// Each attempt gets its own deadline.
+	n := 0
+	err := p.do(context.Background(), "step", nil, func(ctx context.Context) error {
+		n++
+		if n < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("got %v after %d attempts", err, n)
+	}
+
+	// Errors that are not retryable stop at once.
+	n = 0
+	boom := errors.New("boom")
+	err = p.do(context.Background(), "step", func(error) bool { return false }, func(ctx context.Context) error {
+		n++
+		return boom
+	})
+	var se *StepError
+	if !errors.As(err, &se) || se.Step != "step" || se.Attempts != 1 || !errors.Is(err, boom) || n != 1 {
+		t.Fatalf("got %v after %d attempts", err, n)
+	}
+
+	// Attempts run out.
+	n = 0
+	err = p.do(context.Background(), "step", nil, func(ctx context.Context) error { n++; return boom })
+	if !errors.As(err, &se) || se.Attempts != 3 || n != 3 {
+		t.Fatalf("got %v after %d attempts", err, n)
+	}
+}
+
+func TestRetryPoliciesResolveAttempts(t *testing.T) {
+	pol := retryPolicies(map[string]RetryPolicy{StepRename: {Timeout: time.Second}}, 2)
+	if pol[StepConfigure].Attempts != 3 || pol[StepRun].Attempts != 3 {
+		t.Errorf("configure/run attempts = %d/%d, want 1 + MaxRetries", pol[StepConfigure].Attempts, pol[StepRun].Attempts)
+	}
+	if pol[StepSave].Attempts != 1 || pol[StepRename].Attempts != 3 || pol[StepRename].Timeout != time.Second {
+		t.Errorf("unexpected policies %+v / %+v", pol[StepSave], pol[StepRename])
+	}
+}
+
+func TestRunRetriesInFreshBrowser(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{hang: map[string]int{"SetInstance:c7g.xlarge": 1}}
+	o := fakeOrchestrator(page)
+	o.Retry[StepConfigure] = RetryPolicy{Attempts: 1, Timeout: 20 * time.Millisecond}
+	opened := 0
+	o.openPage = func(ctx context.Context, cfg pageConfig) (CalculatorPage, error) {
+		opened++
+		return page, nil
+	}
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatalf("expected the timed out step to be retried in a fresh browser: %v", err)
+	}
+	if opened != 2 || res.ShareURL == "" {
+		t.Fatalf("opened %d browsers, result %+v", opened, res)
+	}
+	// The first item was checkpointed and is not added again.
+	if n := strings.Count(strings.Join(page.calls, ","), "SaveAndAdd"); n != 2 {
+		t.Errorf("saved %d times, want 2: %v", n, page.calls)
+	}
+}
+
+func TestRunDoesNotRetrySaveTimeout(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{hang: map[string]int{"SaveAndAdd": 1}}
+	o := fakeOrchestrator(page)
+	o.Retry[StepSave] = RetryPolicy{Attempts: 1, Timeout: 20 * time.Millisecond}
+	_, err := o.Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "save/add EC2 m7g.large") {
+		t.Fatalf("expected the save timeout, got %v", err)
+	}
+	if n := strings.Count(strings.Join(page.calls, ","), "Open:"); n != 1 {
+		t.Errorf("opened the calculator %d times: %v", n, page.calls)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{hang: map[string]int{"Share": 1}}
+	o := fakeOrchestrator(page)
+	o.Timeout = 50 * time.Millisecond
+	start := time.Now()
+	_, err := o.Run(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the run deadline, got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("run took %s past its 50ms deadline", d)
+	}
+	if strings.Count(strings.Join(page.calls, ","), "Open:") != 1 {
+		t.Errorf("retried after the run deadline: %v", page.calls)
+	}
+}
@@ -0,0 +1,153 @@
+package calc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ---- Usage schedules (24x7 vs. partial utilization) ----
+
+// Schedule is a share of the plan running only part of the month, e.g. dev
+// and test boxes running 8 hours × 22 business days. Share is the fraction of
+// the target (MRR or workload) planned on this schedule.
+type Schedule struct {
+	Name string
+	// HoursPerDay and DaysPerMonth describe the usage; both zero means 24x7.
+	HoursPerDay  float64
+	DaysPerMonth float64
+	Share        float64
+}
+
+// AlwaysOn reports whether the schedule runs 24x7.
+func (s Schedule) AlwaysOn() bool {
+	return s.HoursPerDay == 0 && s.DaysPerMonth == 0
+}
+
+// Hours is the monthly usage of the schedule; 24x7 runs hpm hours.
+func (s Schedule) Hours(hpm float64) float64 {
+	if s.AlwaysOn() {
+		return hpm
+	}
+	return math.Min(hpm, s.HoursPerDay*s.DaysPerMonth)
+}
+
+// Pattern is the schedule usage in the syntax accepted by ParseSchedules.
+func (s Schedule) Pattern() string {
+	if s.AlwaysOn() {
+		return "24x7"
+	}
+	return fmt.Sprintf("%gx%g", s.HoursPerDay, s.DaysPerMonth)
+}
+
+// ParseSchedules reads "name:usage:share" items separated by commas, e.g.
+// "prod:24x7:0.7,dev:8x22:0.3". Usage is 24x7 or hours/day x days/month;
+// shares must add up to 1 and may be omitted for a single schedule.
+func ParseSchedules(s string) ([]Schedule, error) {
+	var out []Schedule
+	total := 0.0
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid schedule %q (use name:usage:share, e.g. dev:8x22:0.3)", item)
+		}
+		sc := Schedule{Name: strings.TrimSpace(parts[0]), Share: 1}
+		usage := strings.ToLower(strings.TrimSpace(parts[1]))
+		if usage != "24x7" {
+			h, d, ok := strings.Cut(usage, "x")
+			var err1, err2 error
+			sc.HoursPerDay, err1 = strconv.ParseFloat(h, 64)
+			sc.DaysPerMonth, err2 = strconv.ParseFloat(d, 64)
+			if !ok || err1 != nil || err2 != nil || sc.HoursPerDay <= 0 || sc.HoursPerDay > 24 || sc.DaysPerMonth <= 0 || sc.DaysPerMonth > 31 {
+				return nil, fmt.Errorf("invalid usage %q in schedule %q (use 24x7 or hours/day x days/month, e.g. 8x22)", parts[1], sc.Name)
+			}
+		}
+		if len(parts) == 3 {
+			v, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if err != nil || v <= 0 || v > 1 {
+				return nil, fmt.Errorf("invalid share %q in schedule %q (use a fraction between 0 and 1)", parts[2], sc.Name)
+			}
+			sc.Share = v
+		} else if strings.Contains(s, ",") {
+			return nil, fmt.Errorf("schedule %q needs a share when several schedules are given", sc.Name)
+		}
+		for _, prev := range out {
+			if prev.Name == sc.Name {
+				return nil, fmt.Errorf("duplicate schedule %q", sc.Name)
+			}
+		}
+		total += sc.Share
+		out = append(out, sc)
+	}
+	if len(out) > 0 && math.Abs(total-1) > 0.001 {
+		return nil, fmt.Errorf("schedule shares add up to %.3f, want 1", total)
+	}
+	return out, nil
+}
+
+// reprice recomputes the monthly cost of opts for hours of usage per month.
+// Commitments (RIs and Savings Plans) are billed every hour of the month
+// whether the instance runs or not, so only On-Demand and Spot prices scale.
+func reprice(opts []ec2Option, p PurchaseOption, hours, hpm float64) []ec2Option {
+	if !p.IsCommitment() && hours < hpm {
+		hpm = hours
+	}
+	out := make([]ec2Option, len(opts))
+	for i, o := range opts {
+		o.Hours = hours
+		o.Recurring = o.Hourly * hpm
+		o.Monthly = o.Recurring
+		if m := p.termMonths(); m > 0 {
+			o.Monthly += o.Upfront / float64(m)
+		}
+		out[i] = o
+	}
+	return out
+}
+
+// scaled is the part of the workload planned on a schedule.
+func (w WorkloadTarget) scaled(share float64) WorkloadTarget {
+	if share >= 1 {
+		return w
+	}
+	w.VCPUs = int(math.Ceil(float64(w.VCPUs) * share))
+	w.MemoryGiB *= share
+	return w
+}
+
+// ---- Browser: usage in the EC2 configurator ----
+
+// ensureUsage sets the calculator's usage to hours per month for instances
+// not running 24x7. Like the other configurator helpers it is best effort.
+func ensureUsage(ctx context.Context, sp *SelectorPack, hours, hpm float64, d time.Duration) error {
+	if hours <= 0 || hours >= hpm {
+		return nil
+	}
+	if err := clickElement(ctx, sp.el("usage_unit_trigger"), d); err == nil {
+		_ = chromedp.Run(ctx, chromedp.Sleep(200*time.Millisecond))
+		if err := clickElement(ctx, sp.el("usage_unit_option", "Hours/Month"), d); err != nil && !deepClickButtonByText(ctx, "Hours/Month") {
+			logger(ctx).Warn("could not select usage unit", "unit", "Hours/Month")
+		}
+	} else {
+		logger(ctx).Debug("usage unit menu not found", "err", err)
+	}
+	value := strconv.FormatFloat(math.Round(hours*100)/100, 'f', -1, 64)
+	input, ok := find(ctx, sp.el("usage_input"))
+	if !ok {
+		return fmt.Errorf("usage input not found")
+	}
+	if err := setInputValueJS(ctx, input.s, input.by, value); err != nil {
+		return fmt.Errorf("could not set usage to %s hours/month: %w", value, err)
+	}
+	logger(ctx).Debug("usage set", "hours_per_month", value)
+	return nil
+}
@@ -0,0 +1,117 @@
+package calc
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSchedules(t *testing.T) {
+	s, err := ParseSchedules("prod:24x7:0.7, dev:8x22:0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 || !s[0].AlwaysOn() || s[1].HoursPerDay != 8 || s[1].DaysPerMonth != 22 || s[1].Share != 0.3 {
+		t.Fatalf("unexpected schedules: %+v", s)
+	}
+	if h := s[1].Hours(730); h != 176 {
+		t.Errorf("dev hours = %v, want 176", h)
+	}
+	if h := s[0].Hours(720); h != 720 {
+		t.Errorf("prod hours = %v, want 720", h)
+	}
+	if s[1].Pattern() != "8x22" {
+		t.Errorf("pattern = %q", s[1].Pattern())
+	}
+
+	if one, err := ParseSchedules("batch:12x30"); err != nil || len(one) != 1 || one[0].Share != 1 {
+		t.Errorf("single schedule without share: %+v, %v", one, err)
+	}
+	if none, err := ParseSchedules(""); err != nil || none != nil {
+		t.Errorf("empty schedules: %+v, %v", none, err)
+	}
+	for _, bad := range []string{
+		"prod:24x7:0.6,dev:8x22:0.3", // shares add up to 0.9
+		"prod:24x7,dev:8x22:0.3",     // missing share
+		"dev:25x22",                  // more than 24 hours a day
+		"dev:8",                      // malformed usage
+		"dev:8x22:0.5,dev:8x22:0.5",  // duplicate
+		":24x7",
+	} {
+		if _, err := ParseSchedules(bad); err == nil {
+			t.Errorf("ParseSchedules(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRepriceScalesOnlyOnDemand(t *testing.T) {
+	opts := []ec2Option{{Name: "m7g.large", Hourly: 0.1, Upfront: 120}}
+
+	od := reprice(opts, PurchaseOption{}, 176, 730)
+	if math.Abs(od[0].Monthly-17.6) > 1e-9 || od[0].Hours != 176 {
+		t.Errorf("on-demand monthly = %v (hours %v), want 17.6", od[0].Monthly, od[0].Hours)
+	}
+	full := reprice(opts, PurchaseOption{}, 720, 720)
+	if math.Abs(full[0].Monthly-72) > 1e-9 {
+		t.Errorf("720h month = %v, want 72", full[0].Monthly)
+	}
+
+	ri, err := ParsePurchaseOption("ri-1yr-partial-upfront")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Commitments are billed for the whole month; upfront is amortized over 12.
+	got := reprice(opts, ri, 176, 730)
+	if want := 0.1*730 + 10; math.Abs(got[0].Monthly-want) > 1e-9 {
+		t.Errorf("RI monthly = %v, want %v", got[0].Monthly, want)
+	}
+	if opts[0].Monthly != 0 {
+		t.Error("reprice modified its input")
+	}
+}
+
+func TestPlanWithSchedules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	doc := `
+hours_per_month: 730
+ec2:
+  - {name: m7g.large, hourly: 0.1, vcpus: 2, memory_gib: 8}
+`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EC2_PRICING_YAML", path)
+
+	sched, err := ParseSchedules("prod:24x7:0.5,dev:8x22:0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Orchestrator{RegionCode: "us-east-1", TargetMRR: 730, Tolerance: 0.02, Schedules: sched}
+	plan, err := o.plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// prod: 365 / 73 = 5 instances; dev: 365 / 17.6 ≈ 20.7 instances.
+	counts := map[string]PlanItem{}
+	for _, it := range plan {
+		counts[it.Schedule] = it
+	}
+	if p := counts["prod"]; p.Count != 5 || p.Hours != 0 {
+		t.Errorf("prod item = %+v", p)
+	}
+	if d := counts["dev"]; d.Count != 21 || d.Hours != 176 {
+		t.Errorf("dev item = %+v", d)
+	}
+
+	o.Schedules = nil
+	o.HoursPerMonth = 700
+	o.TargetMRR = 700
+	plan, err = o.plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || math.Abs(plan[0].Monthly-70) > 1e-9 {
+		t.Errorf("hours_per_month override: %+v", plan)
+	}
+}
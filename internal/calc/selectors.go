@@ -1,16 +1,237 @@
 package calc
 
-const (
-	// CreateEstimateXPath locates the initial "Create estimate" button on
-	// the AWS pricing calculator landing page.
-	CreateEstimateXPath = "//button[contains(., 'Create estimate') or .//span[contains(., 'Create estimate')]]"
-	SearchInputCSS      = "input[placeholder*='Search' i],input[placeholder*='Find' i],input[aria-label*='search' i],input[aria-label*='find' i],input[type='search']"
-	EC2ConfigureXPath   = "//div[.//h3[contains(., 'Amazon EC2')]]//button[contains(., 'Configure') or .//span[contains(., 'Configure')]]"
-	SaveAndAddXPath     = "//button[contains(., 'Save and add service') or .//span[contains(., 'Save and add service')]]"
-	EditNameLinkCSS     = "a[data-cy='edit-estimate-name']"
-	EstimateNameInput   = "input[aria-label='Enter Name']"
-	SaveNameButton      = "button[aria-label='Save']"
-	ShareButtonXPath    = "//button[contains(., 'Share') or .//span[contains(., 'Share')]]"
-	ShareLinkXPath      = "//input[contains(@value, 'https://calculator.aws/#/estimate/')]"
-	NumberInstancesCSS  = "input[aria-label*='Number of instances' i]"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+
+	awscalculatorgen "github.com/example/aws-calculator-gen"
 )
+
+// ---- Selector packs: where the flow finds the calculator's controls ----
+
+// EmbeddedSelectorsSource is the SelectorPackInfo.Source of the selector pack
+// embedded in the binary.
+const EmbeddedSelectorsSource = "embedded"
+
+// selectorElements are the logical elements the browser flow looks up; a pack
+// must give each of them at least one selector.
+var selectorElements = []string{
+	"create_estimate", "find_service", "ec2_configure",
+	"ec2_config_header", "instance_count", "any_memory", "any_vcpus",
+	"instance_search", "instance_option", "os_trigger", "os_option",
+	"usage_unit_trigger", "usage_unit_option", "usage_input",
+	"purchase_option", "app_footer", "save_and_add",
+	"view_summary", "edit_name", "name_input", "save_name",
+	"summary_monthly", "summary_upfront", "summary_service_row",
+	"summary_service_monthly",
+	"share_button", "share_dialog", "share_modal_title", "share_agree",
+	"copy_public_link", "share_link_input",
+}
+
+// SelectorPackInfo identifies the selector pack a run used.
+type SelectorPackInfo struct {
+	// Source is the pack file path, or EmbeddedSelectorsSource when the
+	// embedded pack is used unchanged.
+	Source  string
+	Version string
+}
+
+func (i SelectorPackInfo) String() string {
+	if i.Version == "" {
+		return i.Source + " (unversioned)"
+	}
+	return i.Source + " (version " + i.Version + ")"
+}
+
+// SelectorPack maps each logical element of the calculator UI to an ordered
+// fallback chain of CSS/XPath selectors.
+type SelectorPack struct {
+	Info     SelectorPackInfo
+	elements map[string][]selector
+}
+
+type selectorPackDoc struct {
+	Version  string                     `yaml:"version"`
+	Elements map[string][]selectorEntry `yaml:"elements"`
+}
+
+type selectorEntry struct {
+	CSS   string `yaml:"css"`
+	XPath string `yaml:"xpath"`
+}
+
+// parseSelectorPack decodes a pack file. Unknown elements and entries that
+// are not exactly one of css/xpath are errors.
+func parseSelectorPack(b []byte) (selectorPackDoc, map[string][]selector, error) {
+	var doc selectorPackDoc
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return doc, nil, err
+	}
+	known := map[string]bool{}
+	for _, name := range selectorElements {
+		known[name] = true
+	}
+	elems := map[string][]selector{}
+	for name, entries := range doc.Elements {
+		if !known[name] {
+			return doc, nil, fmt.Errorf("unknown element %q", name)
+		}
+		if len(entries) == 0 {
+			return doc, nil, fmt.Errorf("element %q has no selectors", name)
+		}
+		for i, e := range entries {
+			css, xpath := strings.TrimSpace(e.CSS), strings.TrimSpace(e.XPath)
+			switch {
+			case css != "" && xpath == "":
+				elems[name] = append(elems[name], selector{s: css, by: byCSS})
+			case xpath != "" && css == "":
+				elems[name] = append(elems[name], selector{s: xpath, by: byXPath})
+			default:
+				return doc, nil, fmt.Errorf("element %q, selector %d: want exactly one of css or xpath", name, i+1)
+			}
+		}
+	}
+	return doc, elems, nil
+}
+
+// LoadSelectorPack returns the selector pack in use: the pack embedded in the
+// binary, with the elements of the file named by CALC_SELECTORS_YAML (else
+// ./selectors.yaml, if present) replacing its chains. The override's version
+// is the one reported.
+func LoadSelectorPack() (*SelectorPack, error) {
+	doc, elems, err := parseSelectorPack(awscalculatorgen.DefaultSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("embedded selector pack: %w", err)
+	}
+	for _, name := range selectorElements {
+		if len(elems[name]) == 0 {
+			return nil, fmt.Errorf("embedded selector pack: element %q is missing", name)
+		}
+	}
+	sp := &SelectorPack{
+		Info:     SelectorPackInfo{Source: EmbeddedSelectorsSource, Version: doc.Version},
+		elements: elems,
+	}
+
+	path := strings.TrimSpace(os.Getenv("CALC_SELECTORS_YAML"))
+	var b []byte
+	if path != "" {
+		if b, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("could not read selector pack (CALC_SELECTORS_YAML): %w", err)
+		}
+	} else {
+		path = "selectors.yaml"
+		b, err = os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return sp, nil
+		case err != nil:
+			return nil, fmt.Errorf("could not read selector pack: %w", err)
+		}
+	}
+	doc, over, err := parseSelectorPack(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector pack %s: %w", path, err)
+	}
+	for name, chain := range over {
+		sp.elements[name] = chain
+	}
+	sp.Info = SelectorPackInfo{Source: path, Version: doc.Version}
+	return sp, nil
+}
+
+// element is the fallback chain of one logical element, ready to query.
+type element struct {
+	name string
+	sels []selector
+}
+
+// el returns the chain of the named element, with {text} replaced by text.
+func (sp *SelectorPack) el(name string, text ...string) element {
+	e := element{name: name}
+	for _, s := range sp.elements[name] {
+		if len(text) > 0 {
+			s.s = strings.ReplaceAll(s.s, "{text}", text[0])
+		}
+		e.sels = append(e.sels, s)
+	}
+	return e
+}
+
+// present reports whether sel matches the page right now, without waiting.
+func present(ctx context.Context, sel selector) bool {
+	js := fmt.Sprintf(`document.querySelector(%q) !== null`, sel.s)
+	if sel.by == byXPath {
+		js = fmt.Sprintf(`document.evaluate(%q, document, null, XPathResult.FIRST_ORDERED_NODE_TYPE, null).singleNodeValue !== null`, sel.s)
+	}
+	var ok bool
+	return chromedp.Run(ctx, chromedp.Evaluate(js, &ok)) == nil && ok
+}
+
+// find waits until one of the element's selectors matches, trying the chain
+// in order on every pass, and returns the first that does.
+func find(ctx context.Context, e element) (selector, bool) {
+	start := time.Now()
+	for {
+		for _, s := range e.sels {
+			if present(ctx, s) {
+				logger(ctx).Debug("element found", "element", e.name, "selector", s.s, "duration", time.Since(start))
+				return s, true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			logger(ctx).Debug("element not found", "element", e.name, "duration", time.Since(start))
+			return selector{}, false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// findNow returns the first selector of the chain matching right now.
+func findNow(ctx context.Context, e element) (selector, bool) {
+	for _, s := range e.sels {
+		if present(ctx, s) {
+			return s, true
+		}
+	}
+	return selector{}, false
+}
+
+// clickElement clicks the element within d (no limit other than ctx when d is
+// zero).
+func clickElement(ctx context.Context, e element, d time.Duration) error {
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	s, ok := find(ctx, e)
+	if !ok {
+		return fmt.Errorf("not found: %s", e.name)
+	}
+	return clickRobust(ctx, s.s, s.by)
+}
+
+// waitElement waits until the element is visible, within d (no limit other
+// than ctx when d is zero).
+func waitElement(ctx context.Context, e element, d time.Duration) error {
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	s, ok := find(ctx, e)
+	if !ok {
+		return fmt.Errorf("not found: %s", e.name)
+	}
+	return chromedp.Run(ctx, chromedp.WaitVisible(s.s, queryOpts(s.by)...))
+}
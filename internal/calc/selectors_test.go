@@ -0,0 +1,83 @@
+package calc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSelectorPack(t *testing.T) {
+	chdirTemp(t)
+	sp, err := LoadSelectorPack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.Info.Source != EmbeddedSelectorsSource || sp.Info.Version == "" {
+		t.Fatalf("unexpected pack info %+v", sp.Info)
+	}
+	for _, name := range selectorElements {
+		if len(sp.el(name).sels) == 0 {
+			t.Errorf("element %q has no selectors", name)
+		}
+	}
+
+	// Chains keep the pack order and fill in {text}.
+	opt := sp.el("instance_option", "m7g.large")
+	if len(opt.sels) != 3 || opt.sels[0].by != byXPath || !strings.Contains(opt.sels[0].s, "'m7g.large'") || strings.Contains(opt.sels[0].s, "{text}") {
+		t.Errorf("unexpected instance_option chain %+v", opt.sels)
+	}
+	if c := sp.el("instance_count").sels; c[0].by != byCSS || c[1].by != byXPath {
+		t.Errorf("unexpected instance_count chain %+v", c)
+	}
+	if v := sp.el("view_summary").sels[0]; v.s != "#estimate-button" {
+		t.Errorf("view_summary = %+v", v)
+	}
+}
+
+func TestSelectorPackOverride(t *testing.T) {
+	chdirTemp(t)
+	pack := `version: "2027-01-05"
+elements:
+  share_button:
+    - css: button.share
+    - xpath: //button[normalize-space()='Share']
+`
+	if err := os.WriteFile("selectors.yaml", []byte(pack), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sp, err := LoadSelectorPack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sp.Info.String(); got != "selectors.yaml (version 2027-01-05)" {
+		t.Errorf("info = %q", got)
+	}
+	if share := sp.el("share_button").sels; len(share) != 2 || share[0] != (selector{s: "button.share", by: byCSS}) {
+		t.Errorf("share_button not overridden: %+v", share)
+	}
+	// Elements the override leaves out keep the embedded chains.
+	if len(sp.el("find_service").sels) == 0 {
+		t.Error("find_service lost its embedded chain")
+	}
+
+	// CALC_SELECTORS_YAML wins over ./selectors.yaml; a missing file is an error.
+	path := filepath.Join(t.TempDir(), "pack.yaml")
+	t.Setenv("CALC_SELECTORS_YAML", path)
+	if _, err := LoadSelectorPack(); err == nil || !strings.Contains(err.Error(), "CALC_SELECTORS_YAML") {
+		t.Fatalf("expected read error, got %v", err)
+	}
+
+	for _, bad := range []string{
+		"elements:\n  nope:\n    - css: a\n",
+		"elements:\n  share_button:\n    - css: a\n      xpath: //a\n",
+		"elements:\n  share_button: []\n",
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSelectorPack(); err == nil || !strings.Contains(err.Error(), "invalid selector pack") {
+			t.Errorf("%q: expected invalid pack error, got %v", bad, err)
+		}
+	}
+}
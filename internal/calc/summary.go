@@ -0,0 +1,180 @@
+package calc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// ---- Estimate summary: the calculator's own totals, reconciled with the plan ----
+
+// SummaryService is one service row of the calculator's estimate summary.
+type SummaryService struct {
+	// Config is the row's text: service, description and configuration.
+	Config  string
+	Monthly float64
+}
+
+// Summary is what the calculator's estimate summary shows once the services
+// are added.
+type Summary struct {
+	Services []SummaryService
+	Monthly  float64
+	Upfront  float64
+}
+
+// Reconciliation compares the calculator's summary with the plan. Issues
+// lists every difference beyond the tolerance: a service row showing another
+// instance type, a missing or extra row, or a cost off by more than the
+// tolerance.
+type Reconciliation struct {
+	Summary
+	// PlannedMonthly is the plan's recurring monthly cost and PlannedUpfront
+	// its one-time fees, split like the calculator's summary (Monthly also
+	// amortizes the upfront of commitments; the calculator does not).
+	PlannedMonthly float64
+	PlannedUpfront float64
+	// RelativeError is the calculator's monthly total off the planned one,
+	// relative to the planned one.
+	RelativeError float64
+	Issues        []string
+}
+
+// OK reports whether the calculator agrees with the plan.
+func (r Reconciliation) OK() bool { return len(r.Issues) == 0 }
+
+// reconcile matches the summary's service rows with the plan items, in the
+// order they were added, and compares each row's monthly cost with the item's
+// recurring cost, and the monthly and upfront totals with the plan's, within
+// tol (relative).
+func reconcile(plan []PlanItem, s Summary, tol float64) Reconciliation {
+	r := Reconciliation{Summary: s, PlannedMonthly: planRecurring(plan), PlannedUpfront: planUpfront(plan)}
+	if r.PlannedMonthly > 0 {
+		r.RelativeError = math.Abs(s.Monthly-r.PlannedMonthly) / r.PlannedMonthly
+	}
+	for i, it := range plan {
+		want := float64(it.Count) * it.Recurring
+		if i >= len(s.Services) {
+			r.Issues = append(r.Issues, fmt.Sprintf("%d x %s is missing from the summary", it.Count, it.Name))
+			continue
+		}
+		row := s.Services[i]
+		if !strings.Contains(row.Config, it.Name) {
+			r.Issues = append(r.Issues, fmt.Sprintf("service %d is %q, planned %d x %s", i+1, row.Config, it.Count, it.Name))
+		} else if off(row.Monthly, want, tol) {
+			r.Issues = append(r.Issues, fmt.Sprintf("%d x %s costs $%.2f/mo in the calculator, planned $%.2f", it.Count, it.Name, row.Monthly, want))
+		}
+	}
+	for _, row := range s.Services[min(len(plan), len(s.Services)):] {
+		r.Issues = append(r.Issues, fmt.Sprintf("unplanned service %q", row.Config))
+	}
+	if off(s.Monthly, r.PlannedMonthly, tol) {
+		r.Issues = append(r.Issues, fmt.Sprintf("monthly total is $%.2f in the calculator, planned $%.2f (%.1f%% off)", s.Monthly, r.PlannedMonthly, r.RelativeError*100))
+	}
+	if off(s.Upfront, r.PlannedUpfront, tol) {
+		r.Issues = append(r.Issues, fmt.Sprintf("upfront total is $%.2f in the calculator, planned $%.2f", s.Upfront, r.PlannedUpfront))
+	}
+	return r
+}
+
+// off reports whether got differs from want by more than tol of want, or by
+// more than a cent when want is zero.
+func off(got, want, tol float64) bool {
+	return math.Abs(got-want) > math.Max(tol*math.Abs(want), 0.01)
+}
+
+// summaryJS collects the text of the summary's totals and service rows,
+// trying each selector chain in order like find. Row cells are looked up
+// inside their row.
+const summaryJS = `(() => {
+  const all = (s, root) => {
+    if (s[1] === "xpath") {
+      const r = document.evaluate(s[0], root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+      return Array.from({length: r.snapshotLength}, (_, i) => r.snapshotItem(i));
+    }
+    return Array.from(root.querySelectorAll(s[0]));
+  };
+  const first = (chain, root) => {
+    for (const s of chain) {
+      const n = all(s, root);
+      if (n.length) return n;
+    }
+    return [];
+  };
+  const text = n => n ? (n.innerText || n.textContent || "").replace(/\s+/g, " ").trim() : "";
+  return {
+    monthly: text(first(%s, document)[0]),
+    upfront: text(first(%s, document)[0]),
+    services: first(%s, document).map(r => ({config: text(r), monthly: text(first(%s, r)[0])})),
+  };
+})()`
+
+// chainJSON renders the element's chain as [[selector, "css"|"xpath"], ...]
+// for summaryJS.
+func chainJSON(e element) string {
+	chain := make([][2]string, 0, len(e.sels))
+	for _, s := range e.sels {
+		by := "css"
+		if s.by == byXPath {
+			by = "xpath"
+		}
+		chain = append(chain, [2]string{s.s, by})
+	}
+	b, _ := json.Marshal(chain)
+	return string(b)
+}
+
+// readSummary reads the totals and service rows of the estimate summary on
+// the page.
+func readSummary(ctx context.Context, sp *SelectorPack) (Summary, error) {
+	js := fmt.Sprintf(summaryJS,
+		chainJSON(sp.el("summary_monthly")), chainJSON(sp.el("summary_upfront")),
+		chainJSON(sp.el("summary_service_row")), chainJSON(sp.el("summary_service_monthly")))
+	var raw struct {
+		Monthly  string `json:"monthly"`
+		Upfront  string `json:"upfront"`
+		Services []struct {
+			Config  string `json:"config"`
+			Monthly string `json:"monthly"`
+		} `json:"services"`
+	}
+	if err := chromedp.Run(ctx, chromedp.Evaluate(js, &raw)); err != nil {
+		return Summary{}, err
+	}
+	var s Summary
+	var err error
+	if s.Monthly, err = parseUSD(raw.Monthly); err != nil {
+		return Summary{}, fmt.Errorf("monthly total: %w", err)
+	}
+	if raw.Upfront != "" {
+		if s.Upfront, err = parseUSD(raw.Upfront); err != nil {
+			return Summary{}, fmt.Errorf("upfront total: %w", err)
+		}
+	}
+	for i, row := range raw.Services {
+		m, err := parseUSD(row.Monthly)
+		if err != nil {
+			return Summary{}, fmt.Errorf("service %d: %w", i+1, err)
+		}
+		s.Services = append(s.Services, SummaryService{Config: row.Config, Monthly: m})
+	}
+	return s, nil
+}
+
+var usdAmountRE = regexp.MustCompile(`\d[\d,]*(\.\d+)?`)
+
+// parseUSD reads an amount shown by the calculator, e.g. "1,234.56 USD" or
+// "USD 1,234.56".
+func parseUSD(s string) (float64, error) {
+	m := usdAmountRE.FindString(s)
+	if m == "" {
+		return 0, fmt.Errorf("no amount in %q", s)
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64)
+}
@@ -0,0 +1,143 @@
+package calc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	plan := []PlanItem{
+		{Name: "m7g.large", Count: 2, Recurring: 60, Monthly: 60},
+		{Name: "c7g.xlarge", Count: 1, Recurring: 100, Monthly: 100},
+	}
+	good := Summary{
+		Services: []SummaryService{
+			{Config: "Amazon EC2 2 x m7g.large", Monthly: 120.5},
+			{Config: "Amazon EC2 1 x c7g.xlarge", Monthly: 100},
+		},
+		Monthly: 220.5,
+	}
+	if r := reconcile(plan, good, 0.03); !r.OK() || r.PlannedMonthly != 220 || r.RelativeError <= 0 {
+		t.Fatalf("unexpected reconciliation %+v", r)
+	}
+
+	cases := []struct {
+		name string
+		edit func(s *Summary)
+		want string
+	}{
+		{"wrong row", func(s *Summary) { s.Services[1].Config = "Amazon EC2 1 x c7g.2xlarge" }, `service 2 is "Amazon EC2 1 x c7g.2xlarge"`},
+		{"row cost", func(s *Summary) { s.Services[0].Monthly = 240 }, "2 x m7g.large costs $240.00/mo"},
+		{"missing row", func(s *Summary) { s.Services = s.Services[:1] }, "1 x c7g.xlarge is missing"},
+		{"extra row", func(s *Summary) { s.Services = append(s.Services, SummaryService{Config: "Amazon RDS"}) }, `unplanned service "Amazon RDS"`},
+		{"total", func(s *Summary) { s.Monthly = 300 }, "monthly total is $300.00 in the calculator, planned $220.00"},
+		{"upfront", func(s *Summary) { s.Upfront = 1000 }, "upfront total is $1000.00"},
+	}
+	for _, c := range cases {
+		s := good
+		s.Services = append([]SummaryService(nil), good.Services...)
+		c.edit(&s)
+		r := reconcile(plan, s, 0.03)
+		if r.OK() || !strings.Contains(strings.Join(r.Issues, "\n"), c.want) {
+			t.Errorf("%s: issues %q, want %q", c.name, r.Issues, c.want)
+		}
+	}
+}
+
+func TestReconcileCommitments(t *testing.T) {
+	// A 1-year partial upfront RI: the calculator shows the recurring fee
+	// monthly and the upfront apart, while Monthly amortizes the upfront.
+	plan := []PlanItem{
+		{Name: "m7g.large", Count: 2, Recurring: 30, Upfront: 360, Monthly: 60},
+		{Name: "c7g.xlarge", Count: 1, Recurring: 40, Upfront: 480, Monthly: 80},
+	}
+	s := Summary{
+		Services: []SummaryService{
+			{Config: "Amazon EC2 2 x m7g.large", Monthly: 60},
+			{Config: "Amazon EC2 1 x c7g.xlarge", Monthly: 40},
+		},
+		Monthly: 100,
+		Upfront: 1200,
+	}
+	r := reconcile(plan, s, 0.03)
+	if !r.OK() || r.PlannedMonthly != 100 || r.PlannedUpfront != 1200 {
+		t.Fatalf("unexpected reconciliation %+v", r)
+	}
+
+	s.Upfront = 0
+	if r := reconcile(plan, s, 0.03); r.OK() || !strings.Contains(strings.Join(r.Issues, "\n"), "upfront total is $0.00 in the calculator, planned $1200.00") {
+		t.Fatalf("missing upfront not reported: %q", r.Issues)
+	}
+}
+
+func TestParseUSD(t *testing.T) {
+	for in, want := range map[string]float64{"1,234.56 USD": 1234.56, "USD 0.00": 0, "$ 87": 87} {
+		if got, err := parseUSD(in); err != nil || got != want {
+			t.Errorf("parseUSD(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseUSD("—"); err == nil {
+		t.Error("expected an error without an amount")
+	}
+}
+
+func TestRunReconcilesCalculatorTotals(t *testing.T) {
+	chdirTemp(t)
+	page := &fakePage{}
+	o := fakeOrchestrator(page)
+	res, err := o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := res.Calculator; c == nil || !c.OK() || c.Monthly != 220 || len(c.Services) != 2 {
+		t.Fatalf("unexpected calculator totals %+v", res.Calculator)
+	}
+
+	// A wrong row is only a warning by default...
+	wrong := &Summary{Services: []SummaryService{{Config: "2 x m7g.large", Monthly: 120}, {Config: "1 x r7g.xlarge", Monthly: 130}}, Monthly: 250}
+	page = &fakePage{summary: wrong}
+	o = fakeOrchestrator(page)
+	res, err = o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Calculator == nil || len(res.Calculator.Issues) != 2 || res.ShareURL == "" {
+		t.Fatalf("unexpected result %+v", res.Calculator)
+	}
+
+	// ...and fails the run before sharing with StrictTotals.
+	page = &fakePage{summary: wrong}
+	o = fakeOrchestrator(page)
+	o.StrictTotals = true
+	if _, err := o.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "r7g.xlarge") {
+		t.Fatalf("expected a totals error, got %v", err)
+	}
+	for _, c := range page.calls {
+		if c == "Share" {
+			t.Fatal("an estimate differing from the plan was shared")
+		}
+	}
+
+	// Commitments: the fake shows recurring and upfront costs apart, like
+	// the calculator, and StrictTotals accepts them.
+	page = &fakePage{}
+	o = fakeOrchestrator(page)
+	o.StrictTotals = true
+	o.Purchase = PurchaseOption{Kind: PurchaseReserved, Term: 1, Payment: PaymentPartialUpfront}
+	o.Items = []PlanItem{{Name: "m7g.large", Count: 2, Recurring: 30, Upfront: 360, Monthly: 60}}
+	res, err = o.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := res.Calculator; c == nil || !c.OK() || c.Monthly != 60 || c.Upfront != 720 {
+		t.Fatalf("unexpected calculator totals %+v", res.Calculator)
+	}
+
+	// An unreadable summary is a warning unless StrictTotals.
+	page = &fakePage{fail: map[string]int{"ReadSummary": 2}}
+	o = fakeOrchestrator(page)
+	if res, err := o.Run(context.Background()); err != nil || res.Calculator != nil {
+		t.Fatalf("got %+v, %v", res.Calculator, err)
+	}
+}
@@ -0,0 +1,228 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// ---- Planning by workload shape (vCPU / memory) ----
+
+// workloadGrid is the number of buckets per dimension of the covering DP.
+// Capacity is quantized so each target fits in this many buckets.
+const workloadGrid = 120
+
+// ratioTolerance is how far (relative) an instance's GiB per vCPU may be from
+// WorkloadTarget.Ratio and still be considered.
+const ratioTolerance = 0.10
+
+// WorkloadTarget describes the capacity to cover instead of an MRR.
+type WorkloadTarget struct {
+	VCPUs     int
+	MemoryGiB float64
+	// Ratio is an optional GiB-per-vCPU shape (2 = compute, 4 = general
+	// purpose, 8 = memory). When set, only instance types with that shape are
+	// used, and a missing VCPUs or MemoryGiB is derived from the other.
+	Ratio float64
+}
+
+// Resolve fills the dimension derived from Ratio and validates the target.
+func (w WorkloadTarget) Resolve() (WorkloadTarget, error) {
+	if w.VCPUs < 0 || w.MemoryGiB < 0 || w.Ratio < 0 {
+		return w, fmt.Errorf("workload target must not be negative: %+v", w)
+	}
+	if w.Ratio > 0 {
+		if w.MemoryGiB == 0 {
+			w.MemoryGiB = float64(w.VCPUs) * w.Ratio
+		}
+		if w.VCPUs == 0 {
+			w.VCPUs = int(math.Ceil(w.MemoryGiB / w.Ratio))
+		}
+	}
+	if w.VCPUs == 0 && w.MemoryGiB == 0 {
+		return w, fmt.Errorf("workload target needs vCPUs and/or memory")
+	}
+	return w, nil
+}
+
+// planByShape returns the cheapest mix of catalog options that provides at
+// least w.VCPUs vCPUs and w.MemoryGiB GiB while respecting the constraints.
+// Options without vcpus/memory_gib metadata are ignored.
+func planByShape(opts []ec2Option, w WorkloadTarget, cons PlanConstraints) ([]PlanItem, error) {
+	w, err := w.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if err := cons.Validate(); err != nil {
+		return nil, err
+	}
+
+	vUnit := math.Max(1, math.Ceil(float64(w.VCPUs)/workloadGrid))
+	mUnit := math.Max(1, math.Ceil(w.MemoryGiB/workloadGrid))
+	A := int(math.Ceil(float64(w.VCPUs) / vUnit))
+	B := int(math.Ceil(w.MemoryGiB / mUnit))
+
+	// Capacity is rounded down per instance, so any covering found in the
+	// grid also covers the real target.
+	type shaped struct {
+		opt    ec2Option
+		qa, qb int
+	}
+	var qs []shaped
+	for _, o := range opts {
+		if o.Monthly <= 0 || o.VCPUs <= 0 || o.MemoryGiB <= 0 {
+			continue
+		}
+		if w.Ratio > 0 && math.Abs(o.MemoryGiB/float64(o.VCPUs)-w.Ratio)/w.Ratio > ratioTolerance {
+			continue
+		}
+		qa := int(float64(o.VCPUs) / vUnit)
+		qb := int(o.MemoryGiB / mUnit)
+		if qa == 0 && qb == 0 {
+			continue
+		}
+		qs = append(qs, shaped{opt: o, qa: qa, qb: qb})
+	}
+	if len(qs) == 0 {
+		return nil, fmt.Errorf("no EC2 option with vcpus/memory_gib metadata matches the workload %+v", w)
+	}
+
+	lo, hi := cons.countRange()
+	step := cons.step()
+	kdim := 1
+	if cons.MaxTypes > 0 {
+		kdim = cons.MaxTypes + 1
+		if kdim > len(qs)+1 {
+			kdim = len(qs) + 1
+		}
+	}
+	width := (A + 1) * (B + 1)
+	inf := math.Inf(1)
+	prev := make([]float64, kdim*width) // cheapest monthly cost reaching (k, a, b)
+	next := make([]float64, kdim*width)
+	for i := range prev {
+		prev[i] = inf
+	}
+	prev[0] = 0
+	// Per layer t: count of type t taken and the state the run started from.
+	choice := make([][]int32, len(qs))
+	origin := make([][]int32, len(qs))
+	run := make([]float64, width)
+	runCnt := make([]int32, width)
+	runFrom := make([]int32, width)
+
+	for t, q := range qs {
+		ch := make([]int32, kdim*width)
+		or := make([]int32, kdim*width)
+		copy(next, prev)
+		succ := func(idx, c int) int {
+			a, b := idx/(B+1), idx%(B+1)
+			a = min(A, a+c*q.qa)
+			b = min(B, b+c*q.qb)
+			return a*(B+1) + b
+		}
+		for k := 0; k < kdim; k++ {
+			kp := k
+			if kdim > 1 {
+				if k == 0 {
+					continue
+				}
+				kp = k - 1
+			}
+			for i := range run {
+				run[i], runCnt[i], runFrom[i] = inf, 0, -1
+			}
+			// States only grow, so pushing forward in index order sees every
+			// run value final before it is extended.
+			for idx := 0; idx < width; idx++ {
+				if run[idx] < inf {
+					if i := k*width + idx; run[idx] < next[i] {
+						next[i] = run[idx]
+						ch[i] = runCnt[idx]
+						or[i] = runFrom[idx]
+					}
+					if hi == 0 || int(runCnt[idx])+step <= hi {
+						if j := succ(idx, step); j != idx {
+							if v := run[idx] + float64(step)*q.opt.Monthly; v < run[j] {
+								run[j], runCnt[j], runFrom[j] = v, runCnt[idx]+int32(step), runFrom[idx]
+							}
+						}
+					}
+				}
+				if base := prev[kp*width+idx]; base < inf {
+					j := succ(idx, lo)
+					if j == idx {
+						continue
+					}
+					if v := base + float64(lo)*q.opt.Monthly; v < run[j] {
+						run[j], runCnt[j], runFrom[j] = v, int32(lo), int32(idx)
+					}
+				}
+			}
+		}
+		choice[t], origin[t] = ch, or
+		prev, next = next, prev
+	}
+
+	goal := A*(B+1) + B
+	bestK := -1
+	for k := 0; k < kdim; k++ {
+		if prev[k*width+goal] < inf && (bestK < 0 || prev[k*width+goal] < prev[bestK*width+goal]) {
+			bestK = k
+		}
+	}
+	if bestK < 0 {
+		return nil, fmt.Errorf("no EC2 combination covers %d vCPUs / %.1f GiB with constraints %+v", w.VCPUs, w.MemoryGiB, cons)
+	}
+
+	var plan []PlanItem
+	k, idx := bestK, goal
+	for t := len(qs) - 1; t >= 0; t-- {
+		i := k*width + idx
+		c := int(choice[t][i])
+		if c == 0 {
+			continue
+		}
+		plan = append(plan, qs[t].opt.item(c))
+		idx = int(origin[t][i])
+		if kdim > 1 {
+			k--
+		}
+	}
+	return trimPlan(compactPlan(plan), w, cons), nil
+}
+
+// trimPlan drops instances the grid rounding made redundant: starting with
+// the most expensive type, counts are lowered while the real capacity still
+// covers the workload and the constraints still hold.
+func trimPlan(plan []PlanItem, w WorkloadTarget, cons PlanConstraints) []PlanItem {
+	lo, _ := cons.countRange()
+	step := cons.step()
+	covers := func() bool {
+		v, m := planCapacity(plan)
+		return v >= w.VCPUs && m >= w.MemoryGiB
+	}
+	for i := range plan {
+		for plan[i].Count > 0 {
+			c := plan[i].Count
+			if c-step >= lo {
+				plan[i].Count = c - step
+			} else {
+				plan[i].Count = 0
+			}
+			if !covers() {
+				plan[i].Count = c
+				break
+			}
+		}
+	}
+	return compactPlan(plan)
+}
+
+// planCapacity sums the vCPUs and memory provided by a plan.
+func planCapacity(plan []PlanItem) (vcpus int, memGiB float64) {
+	for _, it := range plan {
+		vcpus += it.Count * it.VCPUs
+		memGiB += float64(it.Count) * it.MemoryGiB
+	}
+	return vcpus, memGiB
+}
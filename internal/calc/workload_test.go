@@ -0,0 +1,63 @@
+package calc
+
+import "testing"
+
+func shapeCatalog() []ec2Option {
+	return []ec2Option{
+		{Name: "c7g.large", Monthly: 52.78, VCPUs: 2, MemoryGiB: 4},
+		{Name: "m7g.large", Monthly: 59.57, VCPUs: 2, MemoryGiB: 8},
+		{Name: "r7g.large", Monthly: 78.18, VCPUs: 2, MemoryGiB: 16},
+		{Name: "m7g.8xlarge", Monthly: 953.09, VCPUs: 32, MemoryGiB: 128},
+		{Name: "r7g.xlarge", Monthly: 156.37, VCPUs: 4, MemoryGiB: 32},
+		{Name: "no-meta.large", Monthly: 1},
+	}
+}
+
+func TestPlanByShapeCovers(t *testing.T) {
+	for _, w := range []WorkloadTarget{
+		{VCPUs: 16, MemoryGiB: 64},
+		{VCPUs: 100, MemoryGiB: 200},
+		{VCPUs: 10, MemoryGiB: 300},
+		{VCPUs: 64, Ratio: 2},
+	} {
+		plan, err := planByShape(shapeCatalog(), w, PlanConstraints{})
+		if err != nil {
+			t.Fatalf("%+v: %v", w, err)
+		}
+		rw, _ := w.Resolve()
+		v, m := planCapacity(plan)
+		if v < rw.VCPUs || m < rw.MemoryGiB {
+			t.Fatalf("%+v: plan %v covers only %d vCPU / %.1f GiB", w, plan, v, m)
+		}
+		for _, it := range plan {
+			if it.Name == "no-meta.large" {
+				t.Fatalf("option without metadata used: %v", plan)
+			}
+		}
+	}
+}
+
+func TestPlanByShapeCheapest(t *testing.T) {
+	// 8 vCPU / 32 GiB: 4x m7g.large (238.28) beats 2x r7g.xlarge (312.74).
+	plan, err := planByShape(shapeCatalog(), WorkloadTarget{VCPUs: 8, MemoryGiB: 32}, PlanConstraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].Name != "m7g.large" || plan[0].Count != 4 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanByShapeConstraints(t *testing.T) {
+	cons := PlanConstraints{MaxTypes: 1, Multiple: 2}
+	plan, err := planByShape(shapeCatalog(), WorkloadTarget{VCPUs: 30, MemoryGiB: 100}, cons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 || plan[0].Count%2 != 0 {
+		t.Fatalf("constraints not respected: %+v", plan)
+	}
+	if _, err := planByShape(shapeCatalog(), WorkloadTarget{}, cons); err == nil {
+		t.Fatalf("expected error for empty workload")
+	}
+}
@@ -0,0 +1,92 @@
+// Package calcfixture serves a local stand-in of the AWS Pricing Calculator,
+// so the chromedp flow can run end-to-end against headless Chrome without
+// network access.
+package calcfixture
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+//go:embed calculator.html
+var page string
+
+// Service is one EC2 service saved in the stand-in calculator.
+type Service struct {
+	Instance   string  `json:"instance"`
+	Count      int     `json:"count"`
+	OS         string  `json:"os"`
+	Pricing    string  `json:"pricing"`
+	Term       string  `json:"term"`
+	Payment    string  `json:"payment"`
+	UsageHours float64 `json:"usageHours"`
+	Monthly    float64 `json:"monthly"`
+}
+
+// Estimate is an estimate published through the Share modal.
+type Estimate struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Services []Service `json:"services"`
+}
+
+// Server is an httptest server with the stand-in calculator at "/" and the
+// share endpoint it posts estimates to.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	estimates []Estimate
+}
+
+// NewServer starts a fixture server whose instance table lists prices
+// (instance type → On-Demand hourly USD). Close it when done.
+func NewServer(prices map[string]float64) *Server {
+	js, err := json.Marshal(prices)
+	if err != nil {
+		panic(err)
+	}
+	body := strings.Replace(page, "/*PRICES*/{}", string(js), 1)
+
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	})
+	mux.HandleFunc("POST /api/estimates", s.share)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// share stores the posted estimate and answers with its public link, shaped
+// like the calculator's so the flow accepts it.
+func (s *Server) share(w http.ResponseWriter, r *http.Request) {
+	var e Estimate
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	e.ID = fmt.Sprintf("fixture-%d", len(s.estimates)+1)
+	s.estimates = append(s.estimates, e)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":  e.ID,
+		"url": "https://calculator.aws/#/estimate?id=" + e.ID,
+	})
+}
+
+// Estimates returns the estimates shared so far, oldest first.
+func (s *Server) Estimates() []Estimate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Estimate(nil), s.estimates...)
+}
@@ -0,0 +1,68 @@
+package calcfixture
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServerPage(t *testing.T) {
+	srv := NewServer(map[string]float64{"m7g.large": 0.0816})
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/?region=us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	for _, want := range []string{
+		`const prices = {"m7g.large":0.0816}`,
+		`aria-label="Find Service"`,
+		`data-cy="Save and add service-button"`,
+		`data-id="agree-continue"`,
+		`clipboard-inputfield`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+}
+
+func TestServerShare(t *testing.T) {
+	srv := NewServer(nil)
+	defer srv.Close()
+
+	post := `{"name":"MAP • ACME","services":[{"instance":"m7g.large","count":2,"os":"Linux","monthly":119.14}]}`
+	resp, err := http.Post(srv.URL+"/api/estimates", "application/json", strings.NewReader(post))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got struct{ ID, URL string }
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "fixture-1" || got.URL != "https://calculator.aws/#/estimate?id=fixture-1" {
+		t.Fatalf("unexpected reply %+v", got)
+	}
+
+	est := srv.Estimates()
+	if len(est) != 1 || est[0].Name != "MAP • ACME" || len(est[0].Services) != 1 || est[0].Services[0].Count != 2 {
+		t.Fatalf("unexpected estimates %+v", est)
+	}
+
+	resp, err = http.Post(srv.URL+"/api/estimates", "application/json", strings.NewReader("{"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad body: status %d, want 400", resp.StatusCode)
+	}
+}
@@ -0,0 +1,41 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// BundleCommand implements the "bundle" subcommand: a zip of a run's
+// artifacts to attach to a bug report.
+type BundleCommand struct {
+	out io.Writer
+}
+
+// NewBundleCommand returns a BundleCommand writing to stdout.
+func NewBundleCommand() *BundleCommand {
+	return &BundleCommand{out: os.Stdout}
+}
+
+// Name returns the command name.
+func (c *BundleCommand) Name() string { return "bundle" }
+
+// Run zips the folder of the run named by run (runs/<run-id>: snapshots,
+// screenshots, log, plan, result and checkpoint, without the browser profile)
+// into out, default runs/<run-id>.zip, and prints the zip's path.
+func (c *BundleCommand) Run(ctx context.Context, params map[string]string) error {
+	id := strings.TrimSpace(params["run"])
+	if id == "" {
+		return fmt.Errorf("bundle: the run parameter is required (a run ID such as 20261017-153045-acme)")
+	}
+	path, err := calc.WriteRunBundle("", id, strings.TrimSpace(params["out"]))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, path)
+	return nil
+}
@@ -0,0 +1,41 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleCommandRun(t *testing.T) {
+	dir := t.TempDir()
+	run := filepath.Join(dir, "runs", "20261017-120000-acme")
+	if err := os.MkdirAll(run, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(run, "run.log"), []byte("log"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	buf := &bytes.Buffer{}
+	cmd := &BundleCommand{out: buf}
+	if err := cmd.Run(context.Background(), map[string]string{"run": "20261017-120000-acme", "out": "debug.zip"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "debug.zip" {
+		t.Errorf("output = %q", buf.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "debug.zip")); err != nil {
+		t.Error(err)
+	}
+	if err := cmd.Run(context.Background(), map[string]string{}); err == nil {
+		t.Error("expected an error without run")
+	}
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// CatalogImportCommand implements the "catalog import" subcommand.
+type CatalogImportCommand struct {
+	out io.Writer
+}
+
+// NewCatalogImportCommand returns a CatalogImportCommand writing to stdout.
+func NewCatalogImportCommand() *CatalogImportCommand {
+	return &CatalogImportCommand{out: os.Stdout}
+}
+
+// Name returns the command name.
+func (c *CatalogImportCommand) Name() string { return "catalog import" }
+
+// Run converts a locally downloaded AWS Price List EC2 offer file into a
+// pricing.yaml, fully offline.
+// Required parameter: file (offer file, .json or .csv).
+// Optional parameters: regions, os, tenancy (shared, dedicated, host),
+// purchase (on-demand and/or ri-* options, comma-separated), the catalog
+// filters of the map command, and out (default pricing.yaml, "-" for stdout).
+// The filters, discounts and os_surcharges sections of an existing out file
+// are kept.
+func (c *CatalogImportCommand) Run(ctx context.Context, params map[string]string) error {
+	params, err := withConfigFile(params)
+	if err != nil {
+		return err
+	}
+	file := strings.TrimSpace(params["file"])
+	if file == "" {
+		return fmt.Errorf("missing parameter file (AWS Price List EC2 offer file, JSON or CSV)")
+	}
+	f, err := offerFilterFromParams(params)
+	if err != nil {
+		return err
+	}
+
+	im, err := calc.ImportOfferFile(file, f)
+	if err != nil {
+		return err
+	}
+	if im.Count() == 0 {
+		return fmt.Errorf("no EC2 prices in %s match regions=%v os=%v tenancy=%s", file, f.Regions, f.OS, f.Tenancy)
+	}
+
+	out := strings.TrimSpace(params["out"])
+	if out == "" {
+		out = "pricing.yaml"
+	}
+	var base []byte
+	if out != "-" {
+		if b, err := os.ReadFile(out); err == nil {
+			base = b
+		}
+	}
+	doc, err := im.PricingYAML(base)
+	if err != nil {
+		return err
+	}
+	if out == "-" {
+		_, err = c.out.Write(doc)
+		return err
+	}
+	if err := os.WriteFile(out, doc, 0o644); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Imported %d prices for %s into %s\n", im.Count(), strings.Join(im.Regions(), ", "), out)
+	return nil
+}
+
+// offerFilterFromParams reads regions, os, tenancy, purchase and the catalog
+// filters.
+func offerFilterFromParams(params map[string]string) (calc.OfferFilter, error) {
+	var f calc.OfferFilter
+	var err error
+	f.Regions = listParam(params, "regions")
+	if len(f.Regions) == 0 {
+		f.Regions = listParam(params, "region")
+	}
+	for _, v := range listParam(params, "os") {
+		osKey, err := calc.ParseOperatingSystem(v)
+		if err != nil {
+			return f, err
+		}
+		f.OS = append(f.OS, osKey)
+	}
+	if f.Tenancy, err = calc.ParseTenancy(params["tenancy"]); err != nil {
+		return f, err
+	}
+	for _, v := range listParam(params, "purchase") {
+		p, err := calc.ParsePurchaseOption(v)
+		if err != nil {
+			return f, err
+		}
+		f.Purchases = append(f.Purchases, p)
+	}
+	if f.Instances, err = catalogFilterFromParams(params); err != nil {
+		return f, err
+	}
+	return f, f.Validate()
+}
+
+// CatalogLintCommand implements the "catalog lint" subcommand.
+type CatalogLintCommand struct {
+	out io.Writer
+}
+
+// NewCatalogLintCommand returns a CatalogLintCommand writing to stdout.
+func NewCatalogLintCommand() *CatalogLintCommand {
+	return &CatalogLintCommand{out: os.Stdout}
+}
+
+// Name returns the command name.
+func (c *CatalogLintCommand) Name() string { return "catalog lint" }
+
+// Run validates a pricing file (file parameter, default the catalog the
+// planner uses: EC2_PRICING_YAML, ./pricing.yaml or the embedded one) and
+// fails when it has errors. Warnings are reported but only fail the run with
+// strict=true.
+func (c *CatalogLintCommand) Run(ctx context.Context, params map[string]string) error {
+	file := strings.TrimSpace(params["file"])
+	var b []byte
+	var err error
+	if file == "" {
+		b, file, err = calc.ReadPricing()
+	} else {
+		b, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	issues := calc.LintPricing(b)
+	for _, i := range issues {
+		fmt.Fprintf(c.out, "%s: %s\n", file, i)
+	}
+	errs := calc.LintErrors(issues)
+	warns := len(issues) - errs
+	if errs > 0 || (warns > 0 && strings.EqualFold(params["strict"], "true")) {
+		return fmt.Errorf("%s: %d error(s), %d warning(s)", file, errs, warns)
+	}
+	fmt.Fprintf(c.out, "%s: ok (%d warning(s))\n", file, warns)
+	return nil
+}
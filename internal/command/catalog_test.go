@@ -0,0 +1,57 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCatalogImportCommandRun(t *testing.T) {
+	dir := t.TempDir()
+	offer := filepath.Join(dir, "offer.csv")
+	csv := `"SKU","OfferTermCode","TermType","Unit","PricePerUnit","Currency","Product Family","Instance Type","vCPU","Memory","Tenancy","Operating System","License Model","Pre Installed S/W","CapacityStatus","Region Code"
+"S1","A","OnDemand","Hrs","0.1008","USD","Compute Instance","m7i.large","2","8 GiB","Shared","Linux","No License required","NA","Used","us-east-1"
+"S2","A","OnDemand","Hrs","0.1008","USD","Compute Instance","m7i.large","2","8 GiB","Shared","Linux","No License required","NA","Used","eu-west-1"
+`
+	if err := os.WriteFile(offer, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	cmd := &CatalogImportCommand{out: buf}
+	err := cmd.Run(context.Background(), map[string]string{"file": offer, "regions": "us-east-1", "out": "-"})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "us-east-1:") || strings.Contains(out, "eu-west-1") || !strings.Contains(out, "memory_gib: 8") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+
+	if err := cmd.Run(context.Background(), map[string]string{"file": offer, "purchase": "spot", "out": "-"}); err == nil {
+		t.Fatal("expected error for spot")
+	}
+}
+
+func TestCatalogLintCommandRun(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	_ = os.WriteFile(good, []byte("ec2:\n  - name: m7g.large\n    hourly: 0.0816\n"), 0o644)
+	_ = os.WriteFile(bad, []byte("ec2:\n  - name: m7g.large\n    hourly: -1\n"), 0o644)
+
+	buf := &bytes.Buffer{}
+	cmd := &CatalogLintCommand{out: buf}
+	if err := cmd.Run(context.Background(), map[string]string{"file": good}); err != nil {
+		t.Fatalf("good file: %v\n%s", err, buf)
+	}
+	if err := cmd.Run(context.Background(), map[string]string{"file": good, "strict": "true"}); err == nil {
+		t.Fatal("expected strict mode to fail on missing metadata")
+	}
+	if err := cmd.Run(context.Background(), map[string]string{"file": bad}); err == nil || !strings.Contains(buf.String(), "negative price") {
+		t.Fatalf("expected negative price error, got %v\n%s", err, buf)
+	}
+}
@@ -0,0 +1,194 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// withConfigFile merges the parameters declared in the YAML file referenced by
+// the "config" parameter. Values given explicitly via --params win over the
+// file. The file is a flat map using the same keys as --params; lists are
+// joined with commas.
+func withConfigFile(params map[string]string) (map[string]string, error) {
+	path := strings.TrimSpace(params["config"])
+	if path == "" {
+		return params, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	merged := make(map[string]string, len(params)+len(doc))
+	for k, v := range doc {
+		merged[k] = configValue(v)
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+func configValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, it := range t {
+			parts = append(parts, configValue(it))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// optionalIntParam returns the integer value of key, or 0 when it is absent.
+func optionalIntParam(params map[string]string, key string) (int, error) {
+	v, ok := params[key]
+	if !ok || strings.TrimSpace(v) == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// planConstraintsFromParams reads max_types, min_count, max_count and
+// ha_multiple.
+func planConstraintsFromParams(params map[string]string) (calc.PlanConstraints, error) {
+	var c calc.PlanConstraints
+	for _, f := range []struct {
+		key string
+		dst *int
+	}{
+		{"max_types", &c.MaxTypes},
+		{"min_count", &c.MinCount},
+		{"max_count", &c.MaxCount},
+		{"ha_multiple", &c.Multiple},
+	} {
+		n, err := optionalIntParam(params, f.key)
+		if err != nil {
+			return c, err
+		}
+		*f.dst = n
+	}
+	return c, c.Validate()
+}
+
+// listParam splits a comma-separated parameter into trimmed, non-empty items.
+func listParam(params map[string]string, key string) []string {
+	var out []string
+	for _, v := range strings.Split(params[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// catalogFilterFromParams reads include_families, exclude_families,
+// generations, min_generation, arch, include_sizes and exclude_sizes.
+func catalogFilterFromParams(params map[string]string) (calc.CatalogFilter, error) {
+	f := calc.CatalogFilter{
+		IncludeFamilies: listParam(params, "include_families"),
+		ExcludeFamilies: listParam(params, "exclude_families"),
+		IncludeSizes:    listParam(params, "include_sizes"),
+		ExcludeSizes:    listParam(params, "exclude_sizes"),
+	}
+	for _, g := range listParam(params, "generations") {
+		n, err := strconv.Atoi(g)
+		if err != nil {
+			return f, fmt.Errorf("invalid generations: %w", err)
+		}
+		f.Generations = append(f.Generations, n)
+	}
+	var err error
+	if f.MinGeneration, err = optionalIntParam(params, "min_generation"); err != nil {
+		return f, err
+	}
+	if f.Arch, err = calc.NormalizeArch(params["arch"]); err != nil {
+		return f, err
+	}
+	return f, f.Validate()
+}
+
+// workloadFromParams reads vcpus, memory_gib and ratio. It returns nil when
+// neither vcpus nor memory_gib is given, meaning the plan targets arr.
+func workloadFromParams(params map[string]string) (*calc.WorkloadTarget, error) {
+	if strings.TrimSpace(params["vcpus"]) == "" && strings.TrimSpace(params["memory_gib"]) == "" {
+		return nil, nil
+	}
+	var w calc.WorkloadTarget
+	var err error
+	if w.VCPUs, err = optionalIntParam(params, "vcpus"); err != nil {
+		return nil, err
+	}
+	if w.MemoryGiB, err = optionalFloatParam(params, "memory_gib"); err != nil {
+		return nil, err
+	}
+	if w.Ratio, err = optionalFloatParam(params, "ratio"); err != nil {
+		return nil, err
+	}
+	if w, err = w.Resolve(); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// optionalFloatParam returns the float value of key, or 0 when it is absent.
+func optionalFloatParam(params map[string]string, key string) (float64, error) {
+	v, ok := params[key]
+	if !ok || strings.TrimSpace(v) == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// browserConfigFromParams reads chrome_url (the DevTools endpoint of a running
+// Chrome), chrome (binary path), user_data_dir, profile, proxy, window
+// (WIDTHxHEIGHT), no_sandbox and chrome_flags.
+func browserConfigFromParams(params map[string]string) (calc.BrowserConfig, error) {
+	cfg := calc.BrowserConfig{
+		RemoteURL:   strings.TrimSpace(params["chrome_url"]),
+		ExecPath:    strings.TrimSpace(params["chrome"]),
+		UserDataDir: strings.TrimSpace(params["user_data_dir"]),
+		Profile:     strings.TrimSpace(params["profile"]),
+		Proxy:       strings.TrimSpace(params["proxy"]),
+		NoSandbox:   strings.EqualFold(params["no_sandbox"], "true"),
+		Flags:       listParam(params, "chrome_flags"),
+	}
+	if cfg.RemoteURL != "" && !strings.HasPrefix(cfg.RemoteURL, "ws://") && !strings.HasPrefix(cfg.RemoteURL, "wss://") &&
+		!strings.HasPrefix(cfg.RemoteURL, "http://") && !strings.HasPrefix(cfg.RemoteURL, "https://") {
+		return cfg, fmt.Errorf("invalid chrome_url %q (use ws://host:9222/devtools/browser/<id> or http://host:9222)", cfg.RemoteURL)
+	}
+	if v := strings.TrimSpace(params["window"]); v != "" {
+		w, h, ok := strings.Cut(strings.ToLower(v), "x")
+		var err error
+		if ok {
+			if cfg.WindowWidth, err = strconv.Atoi(w); err == nil {
+				cfg.WindowHeight, err = strconv.Atoi(h)
+			}
+		}
+		if !ok || err != nil || cfg.WindowWidth <= 0 || cfg.WindowHeight <= 0 {
+			return cfg, fmt.Errorf("invalid window %q (use WIDTHxHEIGHT, e.g. 1400x1000)", v)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+func TestWithConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deal.yaml")
+	body := "max_types: 3\nha_multiple: 2\nregion: eu-west-1\n"
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := withConfigFile(map[string]string{"config": path, "region": "us-east-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["max_types"] != "3" || got["ha_multiple"] != "2" {
+		t.Fatalf("config values not merged: %#v", got)
+	}
+	if got["region"] != "us-east-1" {
+		t.Fatalf("explicit params must win, got region=%s", got["region"])
+	}
+}
+
+func TestPlanConstraintsFromParams(t *testing.T) {
+	c, err := planConstraintsFromParams(map[string]string{"max_types": "2", "max_count": "10", "ha_multiple": "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.MaxTypes != 2 || c.MaxCount != 10 || c.Multiple != 2 || c.MinCount != 0 {
+		t.Fatalf("unexpected constraints: %+v", c)
+	}
+	if _, err := planConstraintsFromParams(map[string]string{"min_count": "x"}); err == nil {
+		t.Fatalf("expected error for invalid integer")
+	}
+	if _, err := planConstraintsFromParams(map[string]string{"min_count": "5", "max_count": "2"}); err == nil {
+		t.Fatalf("expected error for min > max")
+	}
+}
+
+func TestCatalogFilterFromParams(t *testing.T) {
+	f, err := catalogFilterFromParams(map[string]string{
+		"include_families": "r*, m7g",
+		"generations":      "6,7",
+		"arch":             "Graviton",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.IncludeFamilies) != 2 || f.IncludeFamilies[1] != "m7g" || len(f.Generations) != 2 || f.Arch != "arm64" {
+		t.Fatalf("unexpected filter: %+v", f)
+	}
+	if _, err := catalogFilterFromParams(map[string]string{"arch": "sparc"}); err == nil {
+		t.Fatalf("expected error for unknown arch")
+	}
+}
+
+func TestWorkloadFromParams(t *testing.T) {
+	w, err := workloadFromParams(map[string]string{"arr": "1200"})
+	if err != nil || w != nil {
+		t.Fatalf("expected no workload, got %+v err %v", w, err)
+	}
+	w, err = workloadFromParams(map[string]string{"vcpus": "16", "ratio": "4"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.VCPUs != 16 || w.MemoryGiB != 64 {
+		t.Fatalf("unexpected workload: %+v", w)
+	}
+}
+
+func TestBrowserConfigFromParams(t *testing.T) {
+	cfg, err := browserConfigFromParams(map[string]string{
+		"chrome":       "/opt/chrome/chrome",
+		"proxy":        "http://proxy:3128",
+		"window":       "1920x1080",
+		"no_sandbox":   "true",
+		"chrome_flags": "lang=pt-BR, disable-extensions",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := calc.BrowserConfig{
+		ExecPath:     "/opt/chrome/chrome",
+		Proxy:        "http://proxy:3128",
+		WindowWidth:  1920,
+		WindowHeight: 1080,
+		NoSandbox:    true,
+		Flags:        []string{"lang=pt-BR", "disable-extensions"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("got %+v, want %+v", cfg, want)
+	}
+
+	if cfg, err := browserConfigFromParams(map[string]string{"chrome_url": "ws://127.0.0.1:9222/devtools/browser/abc"}); err != nil || !cfg.Remote() {
+		t.Fatalf("remote: %+v, %v", cfg, err)
+	}
+	for _, bad := range []map[string]string{{"window": "big"}, {"window": "0x10"}, {"chrome_url": "127.0.0.1:9222"}} {
+		if _, err := browserConfigFromParams(bad); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
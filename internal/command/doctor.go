@@ -0,0 +1,173 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// DoctorCommand implements the "doctor" subcommand: a health check of the
+// selector pack against the calculator or a saved snapshot.
+type DoctorCommand struct {
+	out       io.Writer
+	runDoctor func(ctx context.Context, d calc.Doctor) (calc.DoctorReport, error)
+	// openLogger opens the check's log; nil logs nothing.
+	openLogger func(params map[string]string) (*slog.Logger, func(), error)
+}
+
+// NewDoctorCommand returns a DoctorCommand with default dependencies.
+func NewDoctorCommand() *DoctorCommand {
+	return &DoctorCommand{
+		out: os.Stdout,
+		runDoctor: func(ctx context.Context, d calc.Doctor) (calc.DoctorReport, error) {
+			return d.Run(ctx)
+		},
+		openLogger: openLogger,
+	}
+}
+
+// Name returns the command name.
+func (c *DoctorCommand) Name() string { return "doctor" }
+
+// Run checks every element of the selector pack and prints, per step of the
+// flow, whether it resolves, how many nodes each selector of its fallback
+// chain matches and which one wins. file checks a saved HTML snapshot (e.g.
+// tmp.html) instead of the live calculator; region, url, instance and
+// headful=true tune the live check, which stops before sharing; the browser
+// and log parameters of map (chrome_url, no_sandbox, log_format...) apply
+// too. json=true
+// prints the report as JSON. The live check fails when an element does not
+// resolve.
+func (c *DoctorCommand) Run(ctx context.Context, params map[string]string) error {
+	browser, err := browserConfigFromParams(params)
+	if err != nil {
+		return err
+	}
+	d := calc.Doctor{
+		BaseURL:    strings.TrimSpace(params["url"]),
+		RegionCode: strings.TrimSpace(params["region"]),
+		Snapshot:   strings.TrimSpace(params["file"]),
+		Headful:    strings.EqualFold(params["headful"], "true"),
+		Instance:   strings.TrimSpace(params["instance"]),
+		Browser:    browser,
+	}
+	if c.openLogger != nil {
+		l, closeLog, err := c.openLogger(params)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+		d.Logger = l
+	}
+	rep, err := c.runDoctor(ctx, d)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(params["json"], "true") {
+		enc := json.NewEncoder(c.out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doctorJSON(rep)); err != nil {
+			return err
+		}
+	} else if err := c.printReport(rep); err != nil {
+		return err
+	}
+	if n := len(rep.Unresolved()); n > 0 && d.Snapshot == "" {
+		return fmt.Errorf("doctor: %d element(s) do not resolve on %s", n, rep.Source)
+	}
+	return nil
+}
+
+func (c *DoctorCommand) printReport(rep calc.DoctorReport) error {
+	fmt.Fprintf(c.out, "Selector pack: %s\nChecked: %s\n", rep.Selectors, rep.Source)
+	rows := [][]string{{"Step", "Element", "Status", "Winner", "Nodes per selector"}}
+	ok, skipped := 0, 0
+	for _, ch := range rep.Checks {
+		status, winner := "MISSING", "-"
+		switch {
+		case ch.Skipped != "":
+			status = "skipped"
+			skipped++
+		case ch.OK():
+			status = "ok"
+			winner = fmt.Sprintf("#%d %s", ch.Winner+1, ch.Matches[ch.Winner].By)
+			ok++
+		}
+		counts := make([]string, len(ch.Matches))
+		for i, m := range ch.Matches {
+			counts[i] = fmt.Sprint(m.Nodes)
+			if m.Err != "" {
+				counts[i] = "err"
+			}
+		}
+		if ch.Skipped != "" {
+			counts = nil
+		}
+		rows = append(rows, []string{ch.Step, ch.Element, status, winner, strings.Join(counts, " / ")})
+	}
+	s, err := pterm.DefaultTable.WithHasHeader().WithData(rows).Srender()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, s)
+
+	unresolved := rep.Unresolved()
+	for _, ch := range unresolved {
+		fmt.Fprintf(c.out, "%s / %s:\n", ch.Step, ch.Element)
+		for i, m := range ch.Matches {
+			line := fmt.Sprintf("  #%d %s %s", i+1, m.By, m.Selector)
+			if m.Err != "" {
+				line += " (error: " + m.Err + ")"
+			}
+			fmt.Fprintln(c.out, line)
+		}
+	}
+	seen := map[string]bool{}
+	for _, ch := range rep.Checks {
+		if ch.Skipped != "" && !seen[ch.Skipped] {
+			seen[ch.Skipped] = true
+			fmt.Fprintf(c.out, "Skipped %s: %s\n", ch.Step, ch.Skipped)
+		}
+	}
+	fmt.Fprintf(c.out, "%d ok, %d unresolved, %d skipped\n", ok, len(unresolved), skipped)
+	return nil
+}
+
+func doctorJSON(rep calc.DoctorReport) map[string]any {
+	checks := make([]map[string]any, 0, len(rep.Checks))
+	for _, ch := range rep.Checks {
+		sels := make([]map[string]any, 0, len(ch.Matches))
+		for _, m := range ch.Matches {
+			s := map[string]any{"by": m.By, "selector": m.Selector, "nodes": m.Nodes}
+			if m.Err != "" {
+				s["error"] = m.Err
+			}
+			sels = append(sels, s)
+		}
+		e := map[string]any{"step": ch.Step, "element": ch.Element, "ok": ch.OK(), "selectors": sels}
+		if ch.OK() {
+			e["winner"] = ch.Winner + 1
+		}
+		if ch.Skipped != "" {
+			e["skipped"] = ch.Skipped
+		}
+		checks = append(checks, e)
+	}
+	return map[string]any{
+		"tool":       "aws-calculator-gen",
+		"command":    "doctor",
+		"source":     rep.Source,
+		"selectors":  map[string]any{"source": rep.Selectors.Source, "version": rep.Selectors.Version},
+		"checks":     checks,
+		"unresolved": len(rep.Unresolved()),
+	}
+}
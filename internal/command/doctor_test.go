@@ -0,0 +1,77 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+func doctorReport() calc.DoctorReport {
+	return calc.DoctorReport{
+		Source:    "https://calculator.aws/?region=us-east-1",
+		Selectors: calc.SelectorPackInfo{Source: "embedded", Version: "2026-10-17"},
+		Checks: []calc.ElementCheck{
+			{Step: "service finder", Element: "find_service", Winner: 0, Matches: []calc.SelectorMatch{{By: "css", Selector: `input[aria-label="Find Service"]`, Nodes: 1}}},
+			{Step: "EC2 configurator", Element: "save_and_add", Winner: -1, Matches: []calc.SelectorMatch{
+				{By: "css", Selector: "div.appFooter [data-cy='x']"},
+				{By: "xpath", Selector: "//button[", Err: "SyntaxError"},
+			}},
+			{Step: "share link", Element: "share_link_input", Winner: -1, Skipped: "not checked live: agreeing to share publishes the estimate"},
+		},
+	}
+}
+
+func TestDoctorCommandRun(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Doctor
+	cmd := &DoctorCommand{out: buf, runDoctor: func(ctx context.Context, d calc.Doctor) (calc.DoctorReport, error) {
+		got = d
+		return doctorReport(), nil
+	}}
+
+	err := cmd.Run(context.Background(), map[string]string{"region": "sa-east-1", "instance": "c7g.large"})
+	if err == nil || !strings.Contains(err.Error(), "1 element(s) do not resolve") {
+		t.Fatalf("expected unresolved error, got %v", err)
+	}
+	if got.RegionCode != "sa-east-1" || got.Instance != "c7g.large" || got.Snapshot != "" {
+		t.Errorf("unexpected doctor %+v", got)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Selector pack: embedded (version 2026-10-17)",
+		"#1 css",
+		"MISSING",
+		"#2 xpath //button[ (error: SyntaxError)",
+		"Skipped share link: not checked live",
+		"1 ok, 1 unresolved, 1 skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	// A snapshot shows a single page: unresolved elements are reported, not
+	// fatal.
+	buf.Reset()
+	if err := cmd.Run(context.Background(), map[string]string{"file": "tmp.html", "json": "true"}); err != nil {
+		t.Fatalf("snapshot run: %v", err)
+	}
+	if got.Snapshot != "tmp.html" {
+		t.Errorf("snapshot not passed: %+v", got)
+	}
+	var doc struct {
+		Selectors  map[string]string
+		Unresolved int
+		Checks     []map[string]any
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("json: %v\n%s", err, buf.String())
+	}
+	if doc.Selectors["version"] != "2026-10-17" || doc.Unresolved != 1 || len(doc.Checks) != 3 || doc.Checks[0]["winner"] != 1.0 {
+		t.Errorf("unexpected report %+v", doc)
+	}
+}
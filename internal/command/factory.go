@@ -29,4 +29,9 @@ func Resolve(name string) (Command, error) {
 
 func init() {
 	Register(NewMapCommand())
+	Register(NewPlanCommand())
+	Register(NewCatalogImportCommand())
+	Register(NewCatalogLintCommand())
+	Register(NewDoctorCommand())
+	Register(NewBundleCommand())
 }
@@ -0,0 +1,270 @@
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// estimateInputs are the deal and planner inputs shared by the map and plan
+// commands.
+type estimateInputs struct {
+	customer    string
+	description string
+	region      string
+	arr         float64
+	targetMRR   float64
+	constraints calc.PlanConstraints
+	filter      calc.CatalogFilter
+	workload    *calc.WorkloadTarget
+	purchase    calc.PurchaseOption
+	os          string
+	// schedules split the target by usage; hoursPerMonth overrides the
+	// catalog's 24x7 month (0 keeps it).
+	schedules     []calc.Schedule
+	hoursPerMonth float64
+	// ramp lists the ARR of each period of a consumption ramp; with
+	// rampEstimates the map command creates one estimate per period.
+	ramp          []calc.RampPeriod
+	rampEstimates bool
+	// alternatives is how many ranked plans to offer; choice preselects one
+	// of them (1-based, 0 asks).
+	alternatives int
+	choice       int
+	// timeout is the deadline of the browser flow and maxRetries how often
+	// a failed step or run is tried again.
+	timeout    time.Duration
+	maxRetries int
+	// strictTotals fails a run whose calculator totals differ from the plan.
+	strictTotals bool
+	// headful shows the browser (the default; headful=false hides it) and
+	// browser configures it.
+	headful bool
+	browser calc.BrowserConfig
+	// logger is the log of the orchestrators built from the inputs.
+	logger *slog.Logger
+}
+
+// defaultRunTimeout is the deadline of a map run's browser flow when the
+// timeout parameter is not given.
+const defaultRunTimeout = 20 * time.Minute
+
+// readEstimateInputs reads the parameters documented on MapCommand.Run,
+// merging the config file and prompting for missing deal information.
+func readEstimateInputs(params map[string]string) (estimateInputs, error) {
+	var in estimateInputs
+	params, err := withConfigFile(params)
+	if err != nil {
+		return in, err
+	}
+	in.constraints, err = planConstraintsFromParams(params)
+	if err != nil {
+		return in, err
+	}
+	in.filter, err = catalogFilterFromParams(params)
+	if err != nil {
+		return in, err
+	}
+
+	in.customer, err = getStringParam(params, "customer", "Customer name")
+	if err != nil {
+		return in, err
+	}
+	in.description, err = getStringParam(params, "description", "Deal description")
+	if err != nil {
+		return in, err
+	}
+
+	in.region, err = getStringParam(params, "region", "Select region")
+	if err != nil {
+		return in, err
+	}
+	if in.region == "" {
+		in.region, _ = pterm.DefaultInteractiveSelect.
+			WithOptions([]string{"us-east-1", "us-east-2", "us-west-2", "eu-west-1", "sa-east-1"}).
+			Show("Select region")
+	}
+
+	in.workload, err = workloadFromParams(params)
+	if err != nil {
+		return in, err
+	}
+	in.purchase, err = calc.ParsePurchaseOption(params["purchase"])
+	if err != nil {
+		return in, err
+	}
+	in.os, err = calc.ParseOperatingSystem(params["os"])
+	if err != nil {
+		return in, err
+	}
+	if in.schedules, err = calc.ParseSchedules(params["schedules"]); err != nil {
+		return in, err
+	}
+	if v := params["hours_per_month"]; v != "" {
+		in.hoursPerMonth, err = strconv.ParseFloat(v, 64)
+		if err != nil || in.hoursPerMonth <= 0 || in.hoursPerMonth > 744 {
+			return in, fmt.Errorf("invalid hours_per_month %q (use hours between 1 and 744, e.g. 730)", v)
+		}
+	}
+	if in.alternatives, err = optionalIntParam(params, "alternatives"); err != nil {
+		return in, err
+	}
+	if in.choice, err = optionalIntParam(params, "choice"); err != nil {
+		return in, err
+	}
+	if in.alternatives < 0 || in.choice < 0 {
+		return in, fmt.Errorf("alternatives and choice must not be negative")
+	}
+	in.timeout = defaultRunTimeout
+	if v := params["timeout"]; v != "" {
+		if in.timeout, err = time.ParseDuration(v); err != nil || in.timeout <= 0 {
+			return in, fmt.Errorf("invalid timeout %q (use a duration such as 15m)", v)
+		}
+	}
+	in.headful = !strings.EqualFold(params["headful"], "false")
+	if in.browser, err = browserConfigFromParams(params); err != nil {
+		return in, err
+	}
+	in.maxRetries = 3
+	if v := params["max_retries"]; v != "" {
+		if in.maxRetries, err = strconv.Atoi(v); err != nil || in.maxRetries < 0 {
+			return in, fmt.Errorf("invalid max_retries %q (use a number of retries, e.g. 3)", v)
+		}
+	}
+	in.strictTotals = strings.EqualFold(params["strict_totals"], "true")
+
+	if ramp := params["ramp"]; ramp != "" {
+		if in.workload != nil {
+			return in, fmt.Errorf("ramp plans by ARR; it cannot be combined with vcpus/memory_gib")
+		}
+		if v, ok := params["arr"]; ok {
+			if in.arr, err = strconv.ParseFloat(v, 64); err != nil {
+				return in, err
+			}
+		}
+		if in.ramp, err = calc.ParseRamp(ramp, in.arr); err != nil {
+			return in, err
+		}
+		if in.arr == 0 {
+			// The MAP target is the ramp's peak.
+			in.arr = calc.RampPeak(in.ramp).ARR
+		}
+		in.rampEstimates = strings.EqualFold(params["ramp_estimates"], "true")
+		for _, p := range in.ramp {
+			pterm.Info.Printf("Ramp %s: ARR %.2f USD (MRR %.2f USD)\n", p.Name, p.ARR, p.TargetMRR())
+		}
+	}
+
+	if in.workload == nil {
+		if in.arr == 0 {
+			in.arr, err = getFloatParam(params, "arr", "ARR USD/year")
+			if err != nil {
+				return in, err
+			}
+		}
+		in.targetMRR = in.arr / 12
+		pterm.Info.Printf("Target MRR: %.2f USD\n", in.targetMRR)
+	} else {
+		pterm.Info.Printf("Target workload: %d vCPUs / %.1f GiB\n", in.workload.VCPUs, in.workload.MemoryGiB)
+	}
+	return in, nil
+}
+
+// resolvedParams returns params with the deal information that was asked
+// interactively filled in, so the run can be repeated (e.g. resumed) without
+// prompts.
+func (in estimateInputs) resolvedParams(params map[string]string) map[string]string {
+	out := make(map[string]string, len(params)+4)
+	for k, v := range params {
+		out[k] = v
+	}
+	delete(out, "resume")
+	out["customer"], out["description"], out["region"] = in.customer, in.description, in.region
+	if in.workload == nil {
+		out["arr"] = strconv.FormatFloat(in.arr, 'f', -1, 64)
+	}
+	return out
+}
+
+// orchestrator returns the orchestrator for the inputs.
+func (in estimateInputs) orchestrator() calc.Orchestrator {
+	return calc.Orchestrator{
+		EstimateName:  fmt.Sprintf("MAP • %s", in.customer),
+		RegionCode:    in.region,
+		TargetMRR:     in.targetMRR,
+		Headful:       in.headful,
+		Browser:       in.browser,
+		Tolerance:     0.03,
+		Timeout:       in.timeout,
+		MaxRetries:    in.maxRetries,
+		StrictTotals:  in.strictTotals,
+		Constraints:   in.constraints,
+		Filter:        in.filter,
+		Workload:      in.workload,
+		Purchase:      in.purchase,
+		OS:            in.os,
+		Schedules:     in.schedules,
+		HoursPerMonth: in.hoursPerMonth,
+		Logger:        in.logger,
+	}
+}
+
+// choosePlan returns the index of the ranked plan to use: the choice
+// parameter when given, the only plan, or the one picked with selectPlan.
+func (in estimateInputs) choosePlan(plans []calc.RankedPlan, selectPlan func(labels []string) (int, error)) (int, error) {
+	if in.choice > 0 {
+		if in.choice > len(plans) {
+			return 0, fmt.Errorf("choice %d is out of range: only %d plan(s) found", in.choice, len(plans))
+		}
+		return in.choice - 1, nil
+	}
+	if len(plans) == 1 || selectPlan == nil {
+		return 0, nil
+	}
+	labels := make([]string, len(plans))
+	for i, p := range plans {
+		labels[i] = p.Label()
+		pterm.Info.Printf("%s\n      %s\n", p.Label(), p.Explanation)
+	}
+	return selectPlan(labels)
+}
+
+// selectPlanInteractive asks the user to pick one of labels.
+func selectPlanInteractive(labels []string) (int, error) {
+	picked, err := pterm.DefaultInteractiveSelect.WithOptions(labels).Show("Select plan")
+	if err != nil {
+		return 0, err
+	}
+	for i, l := range labels {
+		if l == picked {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown plan %q", picked)
+}
+
+// rankedPlansJSON renders the alternatives offered to the user.
+func rankedPlansJSON(plans []calc.RankedPlan) []map[string]any {
+	out := make([]map[string]any, 0, len(plans))
+	for _, p := range plans {
+		out = append(out, map[string]any{
+			"rank":          p.Rank,
+			"score":         p.Score,
+			"monthly":       p.Total,
+			"upfront":       p.Upfront,
+			"relativeError": p.RelativeError,
+			"families":      p.Families,
+			"gravitonShare": p.GravitonShare,
+			"instances":     p.Instances,
+			"explanation":   p.Explanation,
+			"items":         planItemsJSON(p.Items),
+		})
+	}
+	return out
+}
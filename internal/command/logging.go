@@ -0,0 +1,46 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// defaultLogFile is where the commands append their log unless log_file says
+// otherwise.
+const defaultLogFile = "aws-calculator-gen.log"
+
+// openLogger returns the logger of the log parameters and the func closing
+// its file: log_file (default aws-calculator-gen.log, "-" for stderr),
+// log_format (text or json, default text) and log_level (debug, info, warn or
+// error, default debug).
+func openLogger(params map[string]string) (*slog.Logger, func(), error) {
+	level := slog.LevelDebug
+	if v := strings.TrimSpace(params["log_level"]); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return nil, nil, fmt.Errorf("invalid log_level %q (use debug, info, warn or error)", v)
+		}
+	}
+	var w io.Writer = os.Stderr
+	closeLog := func() {}
+	if path := strings.TrimSpace(params["log_file"]); path != "-" {
+		if path == "" {
+			path = defaultLogFile
+		}
+		fp, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		w, closeLog = fp, func() { fp.Close() }
+	}
+	l, err := calc.NewLogger(w, params["log_format"], level)
+	if err != nil {
+		closeLog()
+		return nil, nil, err
+	}
+	return l, closeLog, nil
+}
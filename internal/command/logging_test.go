@@ -0,0 +1,40 @@
+package command
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	l, closeLog, err := openLogger(map[string]string{"log_file": path, "log_format": "json", "log_level": "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Info("shown", "run", "r1")
+	closeLog()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e map[string]any
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatalf("want one JSON entry, got %q: %v", b, err)
+	}
+	if e["msg"] != "shown" || e["run"] != "r1" {
+		t.Fatalf("unexpected entry %s", b)
+	}
+
+	for _, params := range []map[string]string{
+		{"log_file": "-", "log_format": "xml"},
+		{"log_file": "-", "log_level": "loud"},
+	} {
+		if _, _, err := openLogger(params); err == nil {
+			t.Errorf("expected an error for %v", params)
+		}
+	}
+}
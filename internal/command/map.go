@@ -5,9 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pterm/pterm"
@@ -20,6 +21,12 @@ type MapCommand struct {
 	out             io.Writer
 	startSpinner    func(text string) (*pterm.SpinnerPrinter, error)
 	runOrchestrator func(ctx context.Context, o calc.Orchestrator) (calc.Result, error)
+	// rankPlans and selectPlan offer alternative plans before the browser
+	// flow; without rankPlans the orchestrator plans on its own.
+	rankPlans  func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error)
+	selectPlan func(labels []string) (int, error)
+	// openLogger opens the run log; nil logs nothing.
+	openLogger func(params map[string]string) (*slog.Logger, func(), error)
 }
 
 // NewMapCommand returns a MapCommand with default dependencies.
@@ -32,6 +39,11 @@ func NewMapCommand() *MapCommand {
 		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
 			return o.Run(ctx)
 		},
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			return o.Plans(n)
+		},
+		selectPlan: selectPlanInteractive,
+		openLogger: openLogger,
 	}
 }
 
@@ -40,253 +52,267 @@ func (c *MapCommand) Name() string { return "map" }
 
 // Run executes the map command.
 // Required parameters are: customer, description, region and arr (annual recurring revenue).
-// Parameters can be provided via --params or will be requested interactively.
+// Optional plan shape parameters are max_types, min_count, max_count and ha_multiple;
+// catalog filters are include_families, exclude_families, generations,
+// min_generation, arch, include_sizes and exclude_sizes.
+// Giving vcpus and/or memory_gib (optionally with ratio, GiB per vCPU) sizes the
+// plan by workload capacity instead, and arr is then derived from the plan.
+// purchase selects the pricing model (on-demand, spot, ri-1yr-no-upfront,
+// compute-sp-3yr-all-upfront, ec2-sp-1yr-partial-upfront, ...) and os the
+// operating system/license (linux, windows, rhel, suse, windows-sql-web,
+// windows-sql-std, windows-sql-ent).
+// schedules splits the target between usage schedules ("prod:24x7:0.7,dev:8x22:0.3")
+// and hours_per_month overrides the length of a 24x7 month (default 730).
+// ramp plans each period of a consumption ramp ("Y1:480000,Y2:960000" or
+// "Y1:40%,Y2:100%" of arr) and ramp_estimates=true creates one estimate per
+// period, with the share URLs in the ramp section of the JSON.
+// Every estimate gets a run folder, runs/<timestamp>-<customer>, with its
+// checkpoint, step snapshots and screenshots, log, plan and result; resume=<run-id>
+// continues a failed one after its last added service, with the inputs it was
+// started with (parameters given alongside override them).
+// timeout bounds the browser flow (default 20m) and max_retries (default 3)
+// is how often a failed item, and a run that timed out, is tried again.
+// The totals the calculator shows in the summary are read back and compared
+// with the plan (the calculator section of the JSON); differences beyond the
+// tolerance are warnings, or fail the run before sharing with
+// strict_totals=true.
+// The browser is shown unless headful=false; chrome_url drives an already
+// running Chrome through its DevTools endpoint, and chrome (binary path),
+// user_data_dir, profile, proxy, window (1400x1000), no_sandbox=true and
+// chrome_flags (comma-separated switches) configure the one started.
+// The log is appended to log_file (default aws-calculator-gen.log, "-" for
+// stderr) as log_format=text or json, from log_level (default debug); every
+// entry carries the run ID and, in the browser flow, the step.
+// Parameters can be provided via --params, a YAML file given as config=<path>,
+// or will be requested interactively.
 func (c *MapCommand) Run(ctx context.Context, params map[string]string) error {
 	pterm.DefaultSection.Println("AWS Calculator Generator")
 
-	customer, err := getStringParam(params, "customer", "Customer name")
-	if err != nil {
-		return err
-	}
-	description, err := getStringParam(params, "description", "Deal description")
-	if err != nil {
-		return err
+	var logger *slog.Logger
+	if c.openLogger != nil {
+		l, closeLog, err := c.openLogger(params)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+		logger = l
 	}
 
-	region, err := getStringParam(params, "region", "Select region")
-	if err != nil {
-		return err
-	}
-	if region == "" {
-		region, _ = pterm.DefaultInteractiveSelect.
-			WithOptions([]string{"us-east-1", "us-east-2", "us-west-2", "eu-west-1", "sa-east-1"}).
-			Show("Select region")
+	resume := strings.TrimSpace(params["resume"])
+	if resume != "" {
+		cp, err := calc.LoadCheckpoint("", resume)
+		if err != nil {
+			return err
+		}
+		pterm.Info.Printf("Resuming run %s: %d of %d service(s) already added\n", cp.RunID, cp.Done, len(cp.Items))
+		merged := map[string]string{}
+		for k, v := range cp.Inputs {
+			merged[k] = v
+		}
+		for k, v := range params {
+			merged[k] = v
+		}
+		params = merged
 	}
 
-	arr, err := getFloatParam(params, "arr", "ARR USD/year")
+	in, err := readEstimateInputs(params)
 	if err != nil {
 		return err
 	}
-
-	targetMRR := arr / 12
-	pterm.Info.Printf("Target MRR: %.2f USD\n", targetMRR)
-
-	orch := calc.Orchestrator{
-		EstimateName: fmt.Sprintf("MAP • %s", customer),
-		RegionCode:   region,
-		TargetMRR:    targetMRR,
-		Headful:      true,
-		Tolerance:    0.03,
-		Timeout:      0,
-		MaxRetries:   3,
+	in.logger = logger
+	orch := in.orchestrator()
+	orch.Resume = resume
+	if resume == "" {
+		orch.RunID = calc.NewRunID(in.customer)
 	}
+	orch.Inputs = in.resolvedParams(params)
 
 	// ==== UI spinners por fases ====
 
 	// 1) Estimating
+	var s1 *pterm.SpinnerPrinter
 	if c.startSpinner != nil {
-		s1, _ := c.startSpinner("⏳ Estimating the right solution...")
-		s1.Success("OK")
-		fmt.Printf("\n")
+		s1, _ = c.startSpinner("⏳ Estimating the right solution...")
 	}
-
-	// 2) Opening calculator / Adding services / Generating link
-	var result calc.Result
-	if c.startSpinner != nil {
-		spin, _ := c.startSpinner("⏳ Opening AWS public calculator...")
-
-		resCh := make(chan calc.Result, 1)
-		errCh := make(chan error, 1)
-
-		go func() {
-			res, runErr := c.runOrchestrator(ctx, orch)
-			if runErr != nil {
-				errCh <- runErr
-				return
-			}
-			resCh <- res
-		}()
-
-		step := 0
-		ticker := time.NewTicker(7 * time.Second)
-		defer ticker.Stop()
-
-	loop:
-		for {
-			select {
-			case <-ticker.C:
-				switch step {
-				case 0:
-					spin.UpdateText("⏳ Adding services...")
-					step = 1
-				case 1:
-					spin.UpdateText("⏳ Generating share link...")
-					step = 2
-				default:
-				}
-			case err = <-errCh:
-				spin.Fail("error: " + err.Error())
-				return err
-			case result = <-resCh:
-				spin.Success("OK")
-				break loop
-			}
-		}
-	} else {
-		result, err = c.runOrchestrator(ctx, orch)
+	var alternatives []calc.RankedPlan
+	// A resumed run keeps the plan of its checkpoint.
+	if c.rankPlans != nil && resume == "" {
+		alternatives, err = c.rankPlans(orch, in.alternatives)
 		if err != nil {
+			if s1 != nil {
+				s1.Fail("error: " + err.Error())
+			}
 			return err
 		}
 	}
-
-	fmt.Printf("\n")
-
-	// ===== Workplan =====
-	const (
-		hourlyRateBRL = 500.0 // pedido: valor/hora BRL 500,00
-		usdToBrl      = 5.5
-		assessmentPct = 0.05
-		maxBudgetUSD  = 75000.0
-		hoursPerDay   = 8.0
-	)
-
-	// Budget a partir do ARR (limitado a 75k)
-	budgetUSD := arr * assessmentPct
-	if budgetUSD > maxBudgetUSD {
-		budgetUSD = maxBudgetUSD
-	}
-	budgetBRL := budgetUSD * usdToBrl
-
-	// Horas totais que o budget cobre para 1 pessoa (informativo)
-	totalHoursBudget := budgetBRL / hourlyRateBRL
-
-	// Fases (% do esforço total)
-	type bucket struct {
-		Key    string
-		Name   string
-		Weight float64 // 30% / 40% / 30% conforme planilha
+	if s1 != nil {
+		s1.Success("OK")
+		fmt.Printf("\n")
 	}
-	buckets := []bucket{
-		{Key: "business_case", Name: "Caso de negócios inicial", Weight: 0.30},
-		{Key: "discovery", Name: "Descoberta inicial", Weight: 0.40},
-		{Key: "strategy", Name: "Análise de estratégia", Weight: 0.30},
+	selected := 0
+	if len(alternatives) > 0 {
+		if selected, err = in.choosePlan(alternatives, c.selectPlan); err != nil {
+			return err
+		}
+		orch.Items = alternatives[selected].Items
 	}
 
-	// Estratégia para dias:
-	// - Primeiro estimamos os *dias totais do projeto* para 1 pessoa: round(totalHoursBudget/8).
-	// - Em seguida, alocamos os dias por fase segundo os pesos 30/40/30.
-	// - O número TOTAL de pessoas é calculado para encaixar o budget: ceil(budget / (sumDays*8*rate)).
-	totalDays := int(math.Round(totalHoursBudget / hoursPerDay))
-	if totalDays < 1 {
-		totalDays = 1
+	// 2) Opening calculator / Adding services / Generating link
+	result, err := c.run(ctx, orch)
+	if err != nil {
+		return err
 	}
 
-	// Aloca dias por fase respeitando soma == totalDays
-	phaseDays := make([]int, len(buckets))
-	remaining := totalDays
-	for i := range buckets {
-		if i == len(buckets)-1 {
-			phaseDays[i] = remaining
-			break
+	// 3) Ramp: um plano (e opcionalmente uma estimativa) por período
+	var ramp []rampPlan
+	if len(in.ramp) > 0 && c.rankPlans != nil {
+		var sel *calc.RankedPlan
+		if len(alternatives) > 0 {
+			sel = &alternatives[selected]
 		}
-		d := int(math.Ceil(float64(totalDays) * buckets[i].Weight))
-		if d < 0 {
-			d = 0
+		if ramp, err = in.planRamp(c.rankPlans, sel); err != nil {
+			return err
 		}
-		phaseDays[i] = d
-		remaining -= d
-		if remaining < 0 {
-			remaining = 0
+		for i := range ramp {
+			if !in.rampEstimates {
+				break
+			}
+			p := &ramp[i]
+			if p.period.TargetMRR() == in.targetMRR && sel != nil {
+				p.shareURL = result.ShareURL
+				continue
+			}
+			po := orch
+			po.Resume, po.RunID = "", calc.NewRunID(in.customer+" "+p.period.Name)
+			po.EstimateName = fmt.Sprintf("%s • %s", orch.EstimateName, p.period.Name)
+			po.TargetMRR = p.period.TargetMRR()
+			po.Items = p.plan.Items
+			res, err := c.run(ctx, po)
+			if err != nil {
+				return fmt.Errorf("ramp period %s: %w", p.period.Name, err)
+			}
+			p.shareURL = res.ShareURL
 		}
 	}
 
-	// Recalcula soma (só por segurança)
-	sumDays := 0
-	for _, d := range phaseDays {
-		sumDays += d
-	}
-	if sumDays == 0 {
-		sumDays = 1
-	}
-
-	// Número total de pessoas (ceil) para usar o teto do budget
-	totalPeople := int(math.Ceil(budgetBRL / (float64(sumDays) * hoursPerDay * hourlyRateBRL)))
-	if totalPeople < 1 {
-		totalPeople = 1
-	}
-
-	// Pessoas por fase (proporcional ao peso; fracionário, como na planilha)
-	phasePeople := make([]float64, len(buckets))
-	for i := range buckets {
-		phasePeople[i] = float64(totalPeople) * buckets[i].Weight
-	}
-
-	// Monta atividades
-	activities := make([]map[string]any, 0, len(buckets))
-	totalHours := 0
-	for i, b := range buckets {
-		days := phaseDays[i]
-		hours := days * int(hoursPerDay)
-		totalHours += hours
-
-		activities = append(activities, map[string]any{
-			"key":            b.Key,
-			"name":           b.Name,
-			"share":          b.Weight,
-			"days":           days,
-			"hours":          hours,          // esforço em horas (8h/dia) — não multiplica por pessoas
-			"peopleFraction": phasePeople[i], // pessoas (fracionário) por fase
-		})
-	}
-
-	// Custo total do assessment: forçamos o teto (pedido)
-	assessmentTotalCostBRL := budgetBRL
+	fmt.Printf("\n")
 
-	workplan := map[string]any{
-		"hourlyRateBRL":    hourlyRateBRL,
-		"usdToBrl":         usdToBrl,
-		"budgetUSD":        budgetUSD,
-		"budgetBRL":        budgetBRL,
-		"totalHoursBudget": totalHoursBudget,
-		"activities":       activities,
-		"totals": map[string]any{
-			"daysTotal":    sumDays,
-			"hoursTotal":   totalHours,
-			"peopleTotal":  totalPeople,
-			"withinBudget": true,
-		},
-		// custo total do projeto em moeda local (BRL)
-		"assessmentTotalCostBRL": assessmentTotalCostBRL,
+	achievedARR := result.AchievedMRR * 12
+	arr := in.arr
+	if in.workload != nil {
+		arr = achievedARR
 	}
+	workplan, totalPeople := buildWorkplan(arr)
 
 	data := map[string]any{
 		"tool":          "aws-calculator-gen",
 		"command":       "map",
-		"customer":      customer,
-		"description":   description,
+		"customer":      in.customer,
+		"description":   in.description,
 		"estimateName":  orch.EstimateName,
+		"runId":         result.RunID,
+		"runDir":        result.RunDir,
 		"shareUrl":      result.ShareURL,
 		"region":        result.RegionLabel,
-		"os":            "Linux",
-		"arch":          "x86",
-		"tenancy":       "Shared",
-		"purchase":      "On-Demand",
+		"os":            calc.OSLabel(in.os),
+		"arch":          calc.PlanArch(result.Items),
+		"tenancy":       calc.TenancyShared,
+		"purchase":      in.purchase.Label(),
+		"catalog":       map[string]any{"source": result.Catalog.Source, "version": result.Catalog.Version},
+		"selectors":     map[string]any{"source": result.Selectors.Source, "version": result.Selectors.Version},
 		"instanceType":  result.InstanceType,
 		"count":         result.Count,
-		"targetMRR":     targetMRR,
+		"targetMRR":     in.targetMRR,
 		"achievedMRR":   result.AchievedMRR,
+		"achievedARR":   achievedARR,
+		"upfront":       result.Upfront,
 		"relativeError": result.RelativeError,
+		"items":         planItemsJSON(result.Items),
 		"workplan":      workplan,
+		"alternatives":  rankedPlansJSON(alternatives),
+		"selectedPlan":  selected + 1,
 		// campo pedido: número total de pessoas do projeto (ceil)
 		"number_of_people": totalPeople,
 	}
 
+	if len(ramp) > 0 {
+		data["ramp"] = rampJSON(ramp)
+	}
+
+	if c := result.Calculator; c != nil {
+		data["calculatorMRR"] = c.Monthly
+		data["calculator"] = calculatorJSON(*c)
+		if !c.OK() {
+			pterm.Warning.Printf("The calculator's estimate differs from the plan:\n  %s\n", strings.Join(c.Issues, "\n  "))
+		}
+	}
+
+	if workload := in.workload; workload != nil {
+		vcpus, mem := 0, 0.0
+		for _, it := range result.Items {
+			vcpus += it.Count * it.VCPUs
+			mem += float64(it.Count) * it.MemoryGiB
+		}
+		data["workload"] = map[string]any{
+			"targetVCPUs":       workload.VCPUs,
+			"targetMemoryGiB":   workload.MemoryGiB,
+			"ratio":             workload.Ratio,
+			"achievedVCPUs":     vcpus,
+			"achievedMemoryGiB": mem,
+		}
+	}
+
 	enc := json.NewEncoder(c.out)
 	enc.SetEscapeHTML(false)
 	enc.SetIndent("", "  ")
 	return enc.Encode(data)
 }
 
+// run runs the browser flow for orch behind a spinner showing its phases.
+func (c *MapCommand) run(ctx context.Context, orch calc.Orchestrator) (calc.Result, error) {
+	if c.startSpinner == nil {
+		return c.runOrchestrator(ctx, orch)
+	}
+	spin, _ := c.startSpinner("⏳ Opening AWS public calculator...")
+
+	resCh := make(chan calc.Result, 1)
+	errCh := make(chan error, 1)
+
+	go func() {
+		res, runErr := c.runOrchestrator(ctx, orch)
+		if runErr != nil {
+			errCh <- runErr
+			return
+		}
+		resCh <- res
+	}()
+
+	step := 0
+	ticker := time.NewTicker(7 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			switch step {
+			case 0:
+				spin.UpdateText("⏳ Adding services...")
+				step = 1
+			case 1:
+				spin.UpdateText("⏳ Generating share link...")
+				step = 2
+			default:
+			}
+		case err := <-errCh:
+			spin.Fail("error: " + err.Error())
+			return calc.Result{}, err
+		case result := <-resCh:
+			spin.Success("OK")
+			return result, nil
+		}
+	}
+}
+
 // getStringParam retrieves a string parameter or asks the user if missing.
 func getStringParam(params map[string]string, key, prompt string) (string, error) {
 	if v, ok := params[key]; ok {
@@ -309,3 +335,47 @@ func getFloatParam(params map[string]string, key, prompt string) (float64, error
 	val, _ := pterm.DefaultInteractiveTextInput.Show(prompt)
 	return strconv.ParseFloat(val, 64)
 }
+
+// calculatorJSON renders the calculator's summary and its reconciliation with
+// the plan for the JSON output.
+func calculatorJSON(c calc.Reconciliation) map[string]any {
+	services := make([]map[string]any, 0, len(c.Services))
+	for _, s := range c.Services {
+		services = append(services, map[string]any{"config": s.Config, "monthly": s.Monthly})
+	}
+	issues := c.Issues
+	if issues == nil {
+		issues = []string{}
+	}
+	return map[string]any{
+		"monthly":        c.Monthly,
+		"upfront":        c.Upfront,
+		"plannedMonthly": c.PlannedMonthly,
+		"plannedUpfront": c.PlannedUpfront,
+		"relativeError":  c.RelativeError,
+		"services":       services,
+		"matchesPlan":    c.OK(),
+		"issues":         issues,
+	}
+}
+
+// planItemsJSON renders plan items for the JSON output.
+func planItemsJSON(items []calc.PlanItem) []map[string]any {
+	out := make([]map[string]any, 0, len(items))
+	for _, it := range items {
+		out = append(out, map[string]any{
+			"instanceType":  it.Name,
+			"os":            calc.OSLabel(it.OS),
+			"count":         it.Count,
+			"vcpus":         it.VCPUs,
+			"memoryGiB":     it.MemoryGiB,
+			"monthly":       it.Monthly,
+			"recurring":     it.Recurring,
+			"upfront":       it.Upfront,
+			"monthlyTotal":  float64(it.Count) * it.Monthly,
+			"schedule":      it.Schedule,
+			"hoursPerMonth": it.Hours,
+		})
+	}
+	return out
+}
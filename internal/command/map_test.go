@@ -3,7 +3,13 @@ package command
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/example/aws-calculator-gen/internal/calc"
 )
@@ -27,6 +33,7 @@ func TestMapCommandRun(t *testing.T) {
 				Count:         1,
 				AchievedMRR:   100,
 				RelativeError: 0.01,
+				Selectors:     calc.SelectorPackInfo{Source: "embedded", Version: "2026-10-17"},
 			}, nil
 		},
 	}
@@ -45,6 +52,12 @@ func TestMapCommandRun(t *testing.T) {
 	if !bytes.Contains(buf.Bytes(), []byte("https://example.com")) {
 		t.Fatalf("unexpected output: %s", buf.String())
 	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"selectors": {
+    "source": "embedded",
+    "version": "2026-10-17"
+  }`)) {
+		t.Fatalf("selector pack not reported: %s", buf.String())
+	}
 }
 
 func TestGetStringParam(t *testing.T) {
@@ -60,3 +73,237 @@ func TestGetFloatParam(t *testing.T) {
 		t.Fatalf("expected 1.5, got %f err %v", v, err)
 	}
 }
+
+func TestMapCommandRunWorkload(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &MapCommand{
+		out: buf,
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			got = o
+			return calc.Result{
+				ShareURL:    "https://example.com",
+				AchievedMRR: 500,
+				Items:       []calc.PlanItem{{Name: "m7g.large", Count: 4, VCPUs: 2, MemoryGiB: 8, Monthly: 125}},
+			}, nil
+		},
+	}
+	params := map[string]string{
+		"customer":    "ACME",
+		"description": "Test",
+		"region":      "us-east-1",
+		"vcpus":       "8",
+		"memory_gib":  "32",
+	}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.Workload == nil || got.Workload.VCPUs != 8 || got.TargetMRR != 0 {
+		t.Fatalf("workload not passed to orchestrator: %+v", got)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"achievedARR": 6000`)) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"arch": "arm64"`)) {
+		t.Fatalf("arch not derived from the plan: %s", buf.String())
+	}
+}
+
+func TestMapCommandRunSelectsAlternative(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &MapCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			return []calc.RankedPlan{
+				{Rank: 1, Total: 100, Items: []calc.PlanItem{{Name: "m7g.large", Count: 1, Monthly: 100}}},
+				{Rank: 2, Total: 101, Items: []calc.PlanItem{{Name: "m7i.large", Count: 1, Monthly: 101}}},
+			}, nil
+		},
+		selectPlan: func(labels []string) (int, error) { return 1, nil },
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			got = o
+			return calc.Result{ShareURL: "https://example.com", AchievedMRR: 101, Items: o.Items}, nil
+		},
+	}
+	params := map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1", "arr": "1200"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Name != "m7i.large" {
+		t.Fatalf("selected plan not passed to orchestrator: %+v", got.Items)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"selectedPlan": 2`)) {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func TestMapCommandRunRampEstimates(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var names []string
+	cmd := &MapCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			count := int(o.TargetMRR / 100)
+			return []calc.RankedPlan{{Rank: 1, Total: o.TargetMRR,
+				Items: []calc.PlanItem{{Name: "m7g.large", Count: count, Monthly: 100}}}}, nil
+		},
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			names = append(names, o.EstimateName)
+			return calc.Result{ShareURL: "https://example.com/" + fmt.Sprint(len(names)), AchievedMRR: o.TargetMRR, Items: o.Items}, nil
+		},
+	}
+	params := map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1",
+		"ramp": "Y1:40%,Y2:100%", "arr": "12000", "ramp_estimates": "true"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// The Y2 period is the main estimate; only Y1 needs one of its own.
+	if len(names) != 2 || names[1] != "MAP • ACME • Y1" {
+		t.Fatalf("unexpected estimates: %v", names)
+	}
+	var out struct {
+		Ramp struct {
+			TargetARR float64 `json:"targetARR"`
+			Periods   []struct {
+				Period   string  `json:"period"`
+				Target   float64 `json:"targetMRR"`
+				ShareURL string  `json:"shareUrl"`
+			} `json:"periods"`
+		} `json:"ramp"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	p := out.Ramp.Periods
+	if out.Ramp.TargetARR != 16800 || len(p) != 2 || p[0].Target != 400 || p[0].ShareURL != "https://example.com/2" || p[1].ShareURL != "https://example.com/1" {
+		t.Fatalf("unexpected ramp: %+v", out.Ramp)
+	}
+}
+
+func TestMapCommandRunResume(t *testing.T) {
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cp := calc.Checkpoint{
+		RunID:        "20261017-101500-acme",
+		EstimateName: "MAP • ACME",
+		RegionCode:   "us-east-1",
+		Items:        []calc.PlanItem{{Name: "m7g.large", Count: 2}},
+		Inputs:       map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1", "arr": "1200"},
+	}
+	b, _ := json.Marshal(cp)
+	if err := os.MkdirAll(filepath.Join(calc.DefaultRunsDir, cp.RunID), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(calc.DefaultRunsDir, cp.RunID, "checkpoint.json"), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &MapCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			t.Fatal("a resumed run must keep the plan of its checkpoint")
+			return nil, nil
+		},
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			got = o
+			return calc.Result{RunID: cp.RunID, ShareURL: "https://example.com", InstanceType: "m7g.large", Count: 2}, nil
+		},
+	}
+	if err := cmd.Run(context.Background(), map[string]string{"resume": cp.RunID}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.Resume != cp.RunID || got.EstimateName != "MAP • ACME" || got.TargetMRR != 100 {
+		t.Fatalf("unexpected orchestrator: %+v", got)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"runId": "20261017-101500-acme"`)) {
+		t.Fatalf("run ID not reported: %s", buf.String())
+	}
+}
+
+func TestMapCommandRunTimeout(t *testing.T) {
+	var got calc.Orchestrator
+	cmd := &MapCommand{
+		out: &bytes.Buffer{},
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			got = o
+			return calc.Result{ShareURL: "https://example.com"}, nil
+		},
+	}
+	params := map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1", "arr": "1200"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if got.Timeout != 20*time.Minute || got.MaxRetries != 3 {
+		t.Fatalf("defaults: timeout %s, max retries %d", got.Timeout, got.MaxRetries)
+	}
+
+	params["timeout"], params["max_retries"] = "5m", "1"
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if got.Timeout != 5*time.Minute || got.MaxRetries != 1 {
+		t.Fatalf("timeout %s, max retries %d", got.Timeout, got.MaxRetries)
+	}
+
+	params["timeout"] = "soon"
+	if err := cmd.Run(context.Background(), params); err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Fatalf("expected an invalid timeout error, got %v", err)
+	}
+}
+
+func TestMapCommandRunCalculatorTotals(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &MapCommand{
+		out: buf,
+		runOrchestrator: func(ctx context.Context, o calc.Orchestrator) (calc.Result, error) {
+			got = o
+			return calc.Result{
+				ShareURL:    "https://example.com",
+				AchievedMRR: 100,
+				Calculator: &calc.Reconciliation{
+					Summary: calc.Summary{
+						Services: []calc.SummaryService{{Config: "Amazon EC2 1 x t3.micro", Monthly: 130}},
+						Monthly:  130,
+					},
+					PlannedMonthly: 100,
+					RelativeError:  0.3,
+					Issues:         []string{"monthly total is $130.00 in the calculator, planned $100.00 (30.0% off)"},
+				},
+			}, nil
+		},
+	}
+	params := map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1", "arr": "1200", "strict_totals": "true"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	if !got.StrictTotals {
+		t.Fatal("strict_totals not passed to the orchestrator")
+	}
+	var out struct {
+		AchievedMRR   float64
+		CalculatorMRR float64
+		Calculator    struct {
+			Monthly        float64
+			PlannedMonthly float64
+			MatchesPlan    bool
+			Services       []map[string]any
+			Issues         []string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.AchievedMRR != 100 || out.CalculatorMRR != 130 || out.Calculator.PlannedMonthly != 100 ||
+		out.Calculator.MatchesPlan || len(out.Calculator.Services) != 1 || len(out.Calculator.Issues) != 1 {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
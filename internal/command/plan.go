@@ -0,0 +1,288 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// PlanCommand implements the "plan" subcommand: the map planner without the
+// browser.
+type PlanCommand struct {
+	out       io.Writer
+	rankPlans func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error)
+	// openLogger opens the planner log; nil logs nothing.
+	openLogger func(params map[string]string) (*slog.Logger, func(), error)
+}
+
+// NewPlanCommand returns a PlanCommand with default dependencies.
+func NewPlanCommand() *PlanCommand {
+	return &PlanCommand{
+		out: os.Stdout,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			return o.Plans(n)
+		},
+		openLogger: openLogger,
+	}
+}
+
+// Name returns the command name.
+func (c *PlanCommand) Name() string { return "plan" }
+
+// Run takes the same parameters as the map command and prints the ranked
+// alternative plans side by side, then the items, totals and derived workplan
+// of the best one (or of choice=N), and the plan of each ramp period, without
+// launching Chrome.
+// export=csv or export=json also writes the chosen plan as an estimate in the
+// layout of the calculator's own export, to out (default stdout, in which
+// case only the export is printed). The log parameters are those of map.
+func (c *PlanCommand) Run(ctx context.Context, params map[string]string) error {
+	export := strings.ToLower(strings.TrimSpace(params["export"]))
+	if export != "" && export != "csv" && export != "json" {
+		return fmt.Errorf("invalid export %q (use csv or json)", params["export"])
+	}
+	out := strings.TrimSpace(params["out"])
+	if export != "" && (out == "" || out == "-") {
+		// Keep stdout clean for the exported document.
+		pterm.DisableOutput()
+		defer pterm.EnableOutput()
+	}
+	pterm.DefaultSection.Println("AWS Calculator Generator — plan (dry run)")
+	in, err := readEstimateInputs(params)
+	if err != nil {
+		return err
+	}
+	if c.openLogger != nil {
+		l, closeLog, err := c.openLogger(params)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+		in.logger = l
+	}
+	plans, err := c.rankPlans(in.orchestrator(), in.alternatives)
+	if err != nil {
+		return err
+	}
+	chosen, err := in.choosePlan(plans, nil)
+	if err != nil {
+		return err
+	}
+	sel := plans[chosen]
+	if export != "" && (out == "" || out == "-") {
+		return c.writeEstimate(c.out, export, in, sel)
+	}
+	if err := c.printAlternatives(plans); err != nil {
+		return err
+	}
+	result := calc.Result{
+		AchievedMRR:   sel.Total,
+		RelativeError: sel.RelativeError,
+		Items:         sel.Items,
+		Upfront:       sel.Upfront,
+	}
+	fmt.Fprintf(c.out, "Plan #%d:\n", sel.Rank)
+
+	achievedARR := result.AchievedMRR * 12
+	arr := in.arr
+	if in.workload != nil {
+		arr = achievedARR
+	}
+	workplan, totalPeople := buildWorkplan(arr)
+
+	if err := c.printItems(result); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Region:         %s\n", in.region)
+	fmt.Fprintf(c.out, "OS / purchase:  %s / %s\n", calc.OSLabel(in.os), in.purchase.Label())
+	if info, err := calc.PricingCatalogInfo(); err == nil {
+		fmt.Fprintf(c.out, "Catalog:        %s\n", info)
+	}
+	if in.workload != nil {
+		vcpus, mem := 0, 0.0
+		for _, it := range result.Items {
+			vcpus += it.Count * it.VCPUs
+			mem += float64(it.Count) * it.MemoryGiB
+		}
+		fmt.Fprintf(c.out, "Workload:       %d vCPUs / %.1f GiB (covered: %d vCPUs / %.1f GiB)\n", in.workload.VCPUs, in.workload.MemoryGiB, vcpus, mem)
+	} else {
+		fmt.Fprintf(c.out, "Target MRR:     %.2f USD\n", in.targetMRR)
+	}
+	fmt.Fprintf(c.out, "Total MRR:      %.2f USD (ARR %.2f USD)\n", result.AchievedMRR, achievedARR)
+	if in.workload == nil {
+		fmt.Fprintf(c.out, "Relative error: %.2f%%\n", result.RelativeError*100)
+	}
+	if result.Upfront > 0 {
+		fmt.Fprintf(c.out, "Upfront:        %.2f USD\n", result.Upfront)
+	}
+	fmt.Fprintln(c.out)
+	if len(in.ramp) > 0 {
+		ramp, err := in.planRamp(c.rankPlans, &sel)
+		if err != nil {
+			return err
+		}
+		if err := c.printRamp(ramp); err != nil {
+			return err
+		}
+	}
+	if err := c.printWorkplan(workplan, totalPeople); err != nil {
+		return err
+	}
+	if export == "" {
+		return nil
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := c.writeEstimate(f, export, in, sel); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Estimate exported to %s\n", out)
+	return nil
+}
+
+// writeEstimate writes the plan as a calculator-style CSV or JSON estimate.
+func (c *PlanCommand) writeEstimate(w io.Writer, format string, in estimateInputs, sel calc.RankedPlan) error {
+	o := in.orchestrator()
+	est := calc.NewEstimate(o.EstimateName, in.region, in.purchase, sel.Items)
+	if info, err := calc.PricingCatalogInfo(); err == nil {
+		est.Catalog = info
+	}
+	if format == "json" {
+		return est.WriteJSON(w)
+	}
+	return est.WriteCSV(w)
+}
+
+// printRamp prints the plan of each ramp period and the ramp totals.
+func (c *PlanCommand) printRamp(ramp []rampPlan) error {
+	rows := [][]string{{"Period", "Target ARR", "Target MRR", "Plan MRR", "Relative error", "Upfront", "Items"}}
+	for _, p := range ramp {
+		lines := make([]string, 0, len(p.plan.Items))
+		for _, it := range p.plan.Items {
+			lines = append(lines, fmt.Sprintf("%d x %s", it.Count, it.Name))
+		}
+		rows = append(rows, []string{
+			p.period.Name,
+			fmt.Sprintf("%.2f", p.period.ARR),
+			fmt.Sprintf("%.2f", p.period.TargetMRR()),
+			fmt.Sprintf("%.2f", p.plan.Total),
+			fmt.Sprintf("%.2f%%", p.plan.RelativeError*100),
+			fmt.Sprintf("%.2f", p.plan.Upfront),
+			strings.Join(lines, "\n"),
+		})
+	}
+	if err := c.printTable(rows); err != nil {
+		return err
+	}
+	targetARR, achievedARR, upfront := rampTotals(ramp)
+	fmt.Fprintf(c.out, "Ramp total:     target ARR %.2f USD, plan ARR %.2f USD", targetARR, achievedARR)
+	if upfront > 0 {
+		fmt.Fprintf(c.out, ", upfront %.2f USD", upfront)
+	}
+	fmt.Fprint(c.out, "\n\n")
+	return nil
+}
+
+// printAlternatives prints the ranked plans as columns of one table, followed
+// by the explanation of each rank.
+func (c *PlanCommand) printAlternatives(plans []calc.RankedPlan) error {
+	header := []string{""}
+	score := []string{"Score"}
+	total := []string{"Monthly"}
+	relErr := []string{"Relative error"}
+	items := []string{"Items"}
+	families := []string{"Families"}
+	graviton := []string{"Graviton"}
+	for _, p := range plans {
+		header = append(header, fmt.Sprintf("#%d", p.Rank))
+		score = append(score, fmt.Sprintf("%.0f", p.Score))
+		total = append(total, fmt.Sprintf("%.2f", p.Total))
+		relErr = append(relErr, fmt.Sprintf("%.2f%%", p.RelativeError*100))
+		lines := make([]string, 0, len(p.Items))
+		for _, it := range p.Items {
+			line := fmt.Sprintf("%d x %s", it.Count, it.Name)
+			if it.Schedule != "" {
+				line += " (" + it.Schedule + ")"
+			}
+			lines = append(lines, line)
+		}
+		items = append(items, strings.Join(lines, "\n"))
+		families = append(families, strings.Join(p.Families, ", "))
+		graviton = append(graviton, fmt.Sprintf("%.0f%%", p.GravitonShare*100))
+	}
+	if err := c.printTable([][]string{header, score, total, relErr, items, families, graviton}); err != nil {
+		return err
+	}
+	for _, p := range plans {
+		fmt.Fprintf(c.out, "#%d: %s\n", p.Rank, p.Explanation)
+	}
+	fmt.Fprintln(c.out)
+	return nil
+}
+
+func (c *PlanCommand) printItems(result calc.Result) error {
+	rows := [][]string{{"Instance type", "OS", "Schedule", "Hours/mo", "Count", "vCPUs", "Memory GiB", "Monthly (each)", "Monthly (total)", "Upfront"}}
+	for _, it := range result.Items {
+		sched, hours := "24x7", "-"
+		if it.Schedule != "" {
+			sched = it.Schedule
+		}
+		if it.Hours > 0 {
+			hours = fmt.Sprintf("%.0f", it.Hours)
+		}
+		rows = append(rows, []string{
+			it.Name,
+			calc.OSLabel(it.OS),
+			sched,
+			hours,
+			fmt.Sprint(it.Count),
+			fmt.Sprint(it.VCPUs),
+			fmt.Sprintf("%.1f", it.MemoryGiB),
+			fmt.Sprintf("%.2f", it.Monthly),
+			fmt.Sprintf("%.2f", float64(it.Count)*it.Monthly),
+			fmt.Sprintf("%.2f", float64(it.Count)*it.Upfront),
+		})
+	}
+	return c.printTable(rows)
+}
+
+func (c *PlanCommand) printWorkplan(workplan map[string]any, totalPeople int) error {
+	rows := [][]string{{"Phase", "Share", "Days", "Hours", "People"}}
+	for _, a := range workplan["activities"].([]map[string]any) {
+		rows = append(rows, []string{
+			fmt.Sprint(a["name"]),
+			fmt.Sprintf("%.0f%%", a["share"].(float64)*100),
+			fmt.Sprint(a["days"]),
+			fmt.Sprint(a["hours"]),
+			fmt.Sprintf("%.1f", a["peopleFraction"].(float64)),
+		})
+	}
+	if err := c.printTable(rows); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Workplan budget: %.2f USD / %.2f BRL — %d people\n",
+		workplan["budgetUSD"].(float64), workplan["budgetBRL"].(float64), totalPeople)
+	return nil
+}
+
+func (c *PlanCommand) printTable(rows [][]string) error {
+	s, err := pterm.DefaultTable.WithHasHeader().WithData(rows).Srender()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, s)
+	return err
+}
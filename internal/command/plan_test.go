@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+func TestPlanCommandRun(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &PlanCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			got = o
+			return []calc.RankedPlan{
+				{Rank: 1, Score: 90, Total: 990, RelativeError: 0.01, Explanation: "closest",
+					Items: []calc.PlanItem{{Name: "m7g.large", Count: 3, Monthly: 330}}},
+				{Rank: 2, Score: 70, Total: 1020, RelativeError: 0.02, Explanation: "x86 only",
+					Items: []calc.PlanItem{{Name: "m7i.large", Count: 2, Monthly: 510}}},
+			}, nil
+		},
+	}
+	params := map[string]string{
+		"customer":    "ACME",
+		"description": "Test",
+		"region":      "us-east-1",
+		"arr":         "12000",
+		"max_types":   "1",
+	}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got.TargetMRR != 1000 || got.Constraints.MaxTypes != 1 {
+		t.Fatalf("inputs not passed to the planner: %+v", got)
+	}
+	out := buf.String()
+	for _, want := range []string{"#2: x86 only", "2 x m7i.large", "Plan #1:", "990.00", "Relative error: 1.00%", "Descoberta inicial", "Workplan budget: 600.00 USD"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPlanCommandChoice(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cmd := &PlanCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			return []calc.RankedPlan{{Rank: 1, Total: 10}}, nil
+		},
+	}
+	params := map[string]string{"customer": "A", "description": "B", "region": "us-east-1", "arr": "120", "choice": "2"}
+	if err := cmd.Run(context.Background(), params); err == nil {
+		t.Fatal("expected out-of-range choice error")
+	}
+}
+
+func TestPlanCommandSchedules(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var got calc.Orchestrator
+	cmd := &PlanCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			got = o
+			return []calc.RankedPlan{{Rank: 1, Total: 1000, Items: []calc.PlanItem{
+				{Name: "m7g.large", Count: 10, Monthly: 70, Schedule: "prod"},
+				{Name: "m7g.large", Count: 17, Monthly: 17.6, Schedule: "dev", Hours: 176},
+			}}}, nil
+		},
+	}
+	params := map[string]string{"customer": "A", "description": "B", "region": "us-east-1", "arr": "12000",
+		"schedules": "prod:24x7:0.7,dev:8x22:0.3", "hours_per_month": "720"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(got.Schedules) != 2 || got.Schedules[1].Name != "dev" || got.HoursPerMonth != 720 {
+		t.Fatalf("schedules not passed to the planner: %+v", got)
+	}
+	if out := buf.String(); !strings.Contains(out, "17 x m7g.large (dev)") || !strings.Contains(out, "176") {
+		t.Errorf("output lacks the dev schedule:\n%s", out)
+	}
+
+	params["hours_per_month"] = "800"
+	if err := cmd.Run(context.Background(), params); err == nil {
+		t.Fatal("expected invalid hours_per_month error")
+	}
+}
+
+func TestPlanCommandRamp(t *testing.T) {
+	buf := &bytes.Buffer{}
+	var targets []float64
+	cmd := &PlanCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			targets = append(targets, o.TargetMRR)
+			return []calc.RankedPlan{{Rank: 1, Total: o.TargetMRR,
+				Items: []calc.PlanItem{{Name: "m7g.large", Count: int(o.TargetMRR / 100), Monthly: 100}}}}, nil
+		},
+	}
+	params := map[string]string{"customer": "A", "description": "B", "region": "us-east-1",
+		"ramp": "Y1:4800,Y2:9600,Y3:12000"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	// The peak (Y3) is the main plan and is not planned twice.
+	if len(targets) != 3 || targets[0] != 1000 || targets[1] != 400 || targets[2] != 800 {
+		t.Fatalf("unexpected planner targets: %v", targets)
+	}
+	out := buf.String()
+	for _, want := range []string{"Y1", "4 x m7g.large", "8 x m7g.large", "Ramp total:     target ARR 26400.00 USD, plan ARR 26400.00 USD"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	params["vcpus"] = "8"
+	if err := cmd.Run(context.Background(), params); err == nil {
+		t.Fatal("expected error combining ramp and workload sizing")
+	}
+}
+
+func TestPlanCommandExport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cmd := &PlanCommand{
+		out: buf,
+		rankPlans: func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error) {
+			return []calc.RankedPlan{{Rank: 1, Total: 990,
+				Items: []calc.PlanItem{{Name: "m7g.large", Count: 3, Monthly: 330, Recurring: 330}}}}, nil
+		},
+	}
+	params := map[string]string{"customer": "ACME", "description": "Test", "region": "us-east-1", "arr": "12000", "export": "csv"}
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "Estimate summary\n") || !strings.Contains(out, "MAP • ACME,US East (N. Virginia),3 x m7g.large,Amazon EC2,0.00,990.00,11880.00") {
+		t.Fatalf("unexpected export:\n%s", out)
+	}
+
+	buf.Reset()
+	path := filepath.Join(t.TempDir(), "estimate.json")
+	params["export"], params["out"] = "json", path
+	if err := cmd.Run(context.Background(), params); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(b), `"Service Name": "Amazon EC2"`) {
+		t.Fatalf("unexpected export file: %s (%v)", b, err)
+	}
+	if !strings.Contains(buf.String(), "Plan #1:") {
+		t.Errorf("tables not printed when exporting to a file:\n%s", buf.String())
+	}
+
+	params["export"] = "xlsx"
+	if err := cmd.Run(context.Background(), params); err == nil {
+		t.Fatal("expected error for unknown export format")
+	}
+}
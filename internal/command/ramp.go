@@ -0,0 +1,75 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/example/aws-calculator-gen/internal/calc"
+)
+
+// rampPlan is the plan of one ramp period and, when an estimate was created
+// for it, the estimate's share URL.
+type rampPlan struct {
+	period   calc.RampPeriod
+	plan     calc.RankedPlan
+	shareURL string
+}
+
+// planRamp plans every ramp period with the best ranked plan for its ARR.
+// Periods whose target is the main target reuse the selected plan.
+func (in estimateInputs) planRamp(rankPlans func(o calc.Orchestrator, n int) ([]calc.RankedPlan, error), selected *calc.RankedPlan) ([]rampPlan, error) {
+	out := make([]rampPlan, 0, len(in.ramp))
+	for _, p := range in.ramp {
+		rp := rampPlan{period: p}
+		if selected != nil && p.TargetMRR() == in.targetMRR {
+			rp.plan = *selected
+		} else {
+			o := in.orchestrator()
+			o.TargetMRR = p.TargetMRR()
+			plans, err := rankPlans(o, 1)
+			if err != nil {
+				return nil, fmt.Errorf("ramp period %s: %w", p.Name, err)
+			}
+			if len(plans) == 0 {
+				return nil, fmt.Errorf("ramp period %s: no plan found", p.Name)
+			}
+			rp.plan = plans[0]
+		}
+		out = append(out, rp)
+	}
+	return out, nil
+}
+
+// rampTotals sums the target and planned ARR and the upfront of all periods.
+func rampTotals(plans []rampPlan) (targetARR, achievedARR, upfront float64) {
+	for _, p := range plans {
+		targetARR += p.period.ARR
+		achievedARR += p.plan.Total * 12
+		upfront += p.plan.Upfront
+	}
+	return targetARR, achievedARR, upfront
+}
+
+// rampJSON renders the ramp periods and totals for the JSON output.
+func rampJSON(plans []rampPlan) map[string]any {
+	periods := make([]map[string]any, 0, len(plans))
+	for _, p := range plans {
+		periods = append(periods, map[string]any{
+			"period":        p.period.Name,
+			"targetARR":     p.period.ARR,
+			"targetMRR":     p.period.TargetMRR(),
+			"achievedMRR":   p.plan.Total,
+			"achievedARR":   p.plan.Total * 12,
+			"relativeError": p.plan.RelativeError,
+			"upfront":       p.plan.Upfront,
+			"items":         planItemsJSON(p.plan.Items),
+			"shareUrl":      p.shareURL,
+		})
+	}
+	targetARR, achievedARR, upfront := rampTotals(plans)
+	return map[string]any{
+		"periods":     periods,
+		"targetARR":   targetARR,
+		"achievedARR": achievedARR,
+		"upfront":     upfront,
+	}
+}
@@ -0,0 +1,11 @@
+// Package awscalculatorgen holds the assets embedded in the binary: the
+// pricing catalog and the calculator selector pack.
+package awscalculatorgen
+
+import _ "embed"
+
+// DefaultPricing is the pricing catalog shipped with the binary, used when
+// neither EC2_PRICING_YAML nor ./pricing.yaml is available.
+//
+//go:embed pricing.yaml
+var DefaultPricing []byte
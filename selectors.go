@@ -0,0 +1,9 @@
+package awscalculatorgen
+
+import _ "embed"
+
+// DefaultSelectors is the selector pack shipped with the binary: where the
+// browser flow finds each control of the AWS Pricing Calculator.
+//
+//go:embed selectors.yaml
+var DefaultSelectors []byte